@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"api/db"
+
+	"github.com/google/uuid"
+)
+
+// A atualização pelo cnpj.biz grava os campos alterados, que viram linhas do
+// histórico com os valores anterior e novo
+func TestCNPJUpdateChangesPhoneEmailAndOwner(t *testing.T) {
+	previous := db.Lead{
+		ID:           uuid.New(),
+		BusinessName: "Padaria Central",
+		Description:  "Cliente antigo",
+	}
+	lead := previous
+
+	phones := applyCNPJData(&lead, map[string]interface{}{
+		"email":             "contato@padariacentral.com.br",
+		"telefone1":         "(11) 3456-7890",
+		"natureza_juridica": "Sociedade Empresária Limitada",
+		"socios": []interface{}{
+			map[string]interface{}{"nome": "Maria Souza", "qualificacao": "Sócia-Administradora"},
+		},
+	})
+
+	setPrimaryPhones(&lead, phones)
+	if len(phones) != 1 || lead.Phone != "+551134567890" {
+		t.Fatalf("expected the primary phone to be set, got %q and %d phones", lead.Phone, len(phones))
+	}
+
+	changed := make(map[string]bool)
+	for _, field := range db.LeadChanges(&previous, &lead) {
+		changed[field] = true
+	}
+	for _, field := range []string{"Phone", "Email", "Owner", "Description"} {
+		if !changed[field] {
+			t.Errorf("expected %s among the changed fields, got %v", field, changed)
+		}
+	}
+
+	if lead.Description != "Cliente antigo\nNatureza Jurídica: Sociedade Empresária Limitada" {
+		t.Errorf("unexpected description %q", lead.Description)
+	}
+}
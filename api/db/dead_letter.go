@@ -0,0 +1,69 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Mensagem que um consumidor desistiu de processar, guardada para inspeção e reenvio
+type DeadLetter struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Queue      string     `gorm:"type:text;index" json:"queue"`
+	Body       string     `gorm:"type:text" json:"body"`
+	Headers    string     `gorm:"type:text" json:"headers"` // headers AMQP em JSON
+	Reason     string     `gorm:"type:text" json:"reason"`
+	Attempts   int        `gorm:"default:0" json:"attempts"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RedrivenAt *time.Time `json:"redriven_at,omitempty"`
+}
+
+func CreateDeadLetter(deadLetter *DeadLetter) error {
+	result := DB.Create(deadLetter)
+	if result.Error != nil {
+		return fmt.Errorf("Falha ao salvar dead letter: %v", result.Error)
+	}
+	return nil
+}
+
+// Lista as dead letters mais recentes. queue vazio lista todas as filas; com
+// redriven false só as que ainda não foram reenviadas.
+func ListDeadLetters(queue string, redriven bool, limit int) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	query := DB.Order("created_at DESC").Limit(limit)
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+	if !redriven {
+		query = query.Where("redriven_at IS NULL")
+	}
+	result := query.Find(&deadLetters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deadLetters, nil
+}
+
+// Retorna nil quando a dead letter não existe
+func GetDeadLetter(id uuid.UUID) (*DeadLetter, error) {
+	var deadLetter DeadLetter
+	result := DB.First(&deadLetter, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &deadLetter, nil
+}
+
+func MarkDeadLetterRedriven(id uuid.UUID, redrivenAt time.Time) error {
+	result := DB.Model(&DeadLetter{}).Where("id = ?", id).Update("redriven_at", redrivenAt)
+	if result.Error != nil {
+		return fmt.Errorf("Falha ao marcar dead letter %s como reenviada: %v", id, result.Error)
+	}
+	return nil
+}
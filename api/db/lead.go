@@ -23,8 +23,9 @@ type Lead struct {
 	ZIPCode               string       `gorm:"type:text"`
 	Owner                 string       `gorm:"type:text"`
 	Source                string       `gorm:"type:text"`
-	Phone                 string       `gorm:"size:50"`
+	Phone                 string       `gorm:"size:50"` // número principal em E.164; todos ficam em Phones
 	Whatsapp              string       `gorm:"size:50"`
+	Phones                []LeadPhone  `gorm:"foreignKey:LeadID" json:",omitempty"`
 	Website               string       `gorm:"type:text"`
 	Email                 string       `gorm:"type:text"`
 	LeadSteps             []LeadStep   `gorm:"foreignKey:LeadID"`
@@ -38,6 +39,7 @@ type Lead struct {
 	UserRatingsTotal      int          `gorm:"default:0"`
 	Vicinity              string       `gorm:"type:text"`
 	PermanentlyClosed     bool         `gorm:"default:false"`
+	OpeningHours          string       `gorm:"type:text"`
 
 	CompanySize    string  `gorm:"size:50"`
 	Revenue        float64 `gorm:"type:numeric"`
@@ -51,21 +53,29 @@ type Lead struct {
 
 	BusinessStatus string `gorm:"type:text"`
 
-	Quality      string `gorm:"size:50"`
-	SearchTerm   string `gorm:"size:50"`
-	FieldsFilled int    `gorm:"default:0"`
-	GoogleId     string `gorm:"type:text"`
+	Quality      string     `gorm:"size:50"`
+	QualityScore float64    `gorm:"type:numeric;default:0"` // 0-100, calculada pelo data-processor
+	ScoredAt     *time.Time // última pontuação do data-processor
+	SearchTerm   string     `gorm:"size:50"`
+	FieldsFilled int        `gorm:"default:0"`
+	GoogleId     string     `gorm:"type:text;uniqueIndex:idx_leads_google_id,where:google_id <> ''"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	// Origem da alteração em andamento, gravada no histórico (não é coluna)
+	ChangeSource string `gorm:"-" json:"-"`
 }
 
+// Devolvido por CreateLead quando já existe um lead com o mesmo GoogleId
+var ErrLeadExists = errors.New("Lead já existe")
+
 func CreateLead(lead *Lead) error {
 	var existingLead Lead
 
 	result := DB.Where("google_id = ?", lead.GoogleId).First(&existingLead)
 	if result.Error == nil {
-		return fmt.Errorf("Lead com GoogleId %s já existe", lead.GoogleId)
+		return fmt.Errorf("%w: GoogleId %s", ErrLeadExists, lead.GoogleId)
 	}
 
 	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
@@ -140,6 +150,7 @@ func GetLeadByID(leadID uuid.UUID) (*Lead, error) {
 	return &lead, nil
 }
 
+// Grava os campos do lead que mudaram em relação ao registro salvo
 func UpdateLead(lead *Lead) error {
 	existingLead, err := GetLeadByID(lead.ID)
 	if err != nil {
@@ -150,15 +161,12 @@ func UpdateLead(lead *Lead) error {
 		return fmt.Errorf("Lead não encontrado para ID: %s", lead.ID)
 	}
 
-	if lead.Description != "" {
-		if existingLead.Description != "" {
-			existingLead.Description = fmt.Sprintf("%s\n%s", existingLead.Description, lead.Description)
-		} else {
-			existingLead.Description = lead.Description
-		}
+	changed := LeadChanges(existingLead, lead)
+	if len(changed) == 0 {
+		return nil
 	}
 
-	result := DB.Save(existingLead)
+	result := DB.Model(lead).Select(changed).Updates(lead)
 	if result.Error != nil {
 		return fmt.Errorf("Erro ao atualizar o lead: %v", result.Error)
 	}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Coluna da tabela leads usada em exportações e importações. Name é o nome
+// da coluna no banco (e no cabeçalho do data-021024.csv).
+type LeadColumn struct {
+	Name    string
+	Field   string            // campo em Lead
+	Default bool              // exportada quando nenhuma coluna é pedida
+	Labels  map[string]string // cabeçalho por idioma
+}
+
+// Formato de data e hora usado pelo dump do Postgres
+const LeadTimeLayout = "2006-01-02 15:04:05.999999-07"
+
+// Na ordem do dump da tabela leads; as colunas que não estavam no dump não
+// são Default e só entram quando pedidas
+var LeadColumns = []LeadColumn{
+	{"id", "ID", true, map[string]string{"pt": "ID", "en": "ID"}},
+	{"business_name", "BusinessName", true, map[string]string{"pt": "Nome fantasia", "en": "Business name"}},
+	{"registered_name", "RegisteredName", true, map[string]string{"pt": "Razão social", "en": "Registered name"}},
+	{"foundation_date", "FoundationDate", true, map[string]string{"pt": "Data de fundação", "en": "Foundation date"}},
+	{"address", "Address", true, map[string]string{"pt": "Endereço", "en": "Address"}},
+	{"city", "City", true, map[string]string{"pt": "Cidade", "en": "City"}},
+	{"state", "State", true, map[string]string{"pt": "Estado", "en": "State"}},
+	{"country", "Country", true, map[string]string{"pt": "País", "en": "Country"}},
+	{"z_ip_code", "ZIPCode", true, map[string]string{"pt": "CEP", "en": "ZIP code"}},
+	{"owner", "Owner", true, map[string]string{"pt": "Sócios", "en": "Owner"}},
+	{"source", "Source", true, map[string]string{"pt": "Origem", "en": "Source"}},
+	{"phone", "Phone", true, map[string]string{"pt": "Telefone", "en": "Phone"}},
+	{"whatsapp", "Whatsapp", true, map[string]string{"pt": "WhatsApp", "en": "WhatsApp"}},
+	{"website", "Website", true, map[string]string{"pt": "Site", "en": "Website"}},
+	{"email", "Email", true, map[string]string{"pt": "E-mail", "en": "Email"}},
+	{"instagram", "Instagram", true, map[string]string{"pt": "Instagram", "en": "Instagram"}},
+	{"facebook", "Facebook", true, map[string]string{"pt": "Facebook", "en": "Facebook"}},
+	{"tik_tok", "TikTok", true, map[string]string{"pt": "TikTok", "en": "TikTok"}},
+	{"company_registration_id", "CompanyRegistrationID", true, map[string]string{"pt": "CNPJ", "en": "Company registration ID"}},
+	{"categories", "Categories", true, map[string]string{"pt": "Categorias", "en": "Categories"}},
+	{"rating", "Rating", true, map[string]string{"pt": "Avaliação", "en": "Rating"}},
+	{"price_level", "PriceLevel", true, map[string]string{"pt": "Nível de preço", "en": "Price level"}},
+	{"user_ratings_total", "UserRatingsTotal", true, map[string]string{"pt": "Total de avaliações", "en": "Total ratings"}},
+	{"vicinity", "Vicinity", true, map[string]string{"pt": "Vizinhança", "en": "Vicinity"}},
+	{"permanently_closed", "PermanentlyClosed", true, map[string]string{"pt": "Fechado permanentemente", "en": "Permanently closed"}},
+	{"company_size", "CompanySize", true, map[string]string{"pt": "Porte", "en": "Company size"}},
+	{"revenue", "Revenue", true, map[string]string{"pt": "Faturamento", "en": "Revenue"}},
+	{"employees_count", "EmployeesCount", true, map[string]string{"pt": "Funcionários", "en": "Employees"}},
+	{"description", "Description", true, map[string]string{"pt": "Descrição", "en": "Description"}},
+	{"primary_activity", "PrimaryActivity", true, map[string]string{"pt": "Atividade principal", "en": "Primary activity"}},
+	{"types", "Types", true, map[string]string{"pt": "Tipos", "en": "Types"}},
+	{"business_status", "BusinessStatus", true, map[string]string{"pt": "Situação", "en": "Business status"}},
+	{"quality", "Quality", true, map[string]string{"pt": "Qualidade", "en": "Quality"}},
+	{"search_term", "SearchTerm", true, map[string]string{"pt": "Termo de busca", "en": "Search term"}},
+	{"fields_filled", "FieldsFilled", true, map[string]string{"pt": "Campos preenchidos", "en": "Fields filled"}},
+	{"google_id", "GoogleId", true, map[string]string{"pt": "Google ID", "en": "Google ID"}},
+	{"created_at", "CreatedAt", true, map[string]string{"pt": "Criado em", "en": "Created at"}},
+	{"updated_at", "UpdatedAt", true, map[string]string{"pt": "Atualizado em", "en": "Updated at"}},
+	{"secondary_activities", "SecondaryActivities", false, map[string]string{"pt": "Atividades secundárias", "en": "Secondary activities"}},
+	{"opening_hours", "OpeningHours", false, map[string]string{"pt": "Horário de funcionamento", "en": "Opening hours"}},
+	{"equity_capital", "EquityCapital", false, map[string]string{"pt": "Capital social", "en": "Equity capital"}},
+	{"quality_score", "QualityScore", false, map[string]string{"pt": "Pontuação", "en": "Quality score"}},
+}
+
+// Colunas do dump original, usadas quando nenhuma é pedida
+var DefaultLeadColumns = defaultLeadColumns()
+
+func defaultLeadColumns() []LeadColumn {
+	var columns []LeadColumn
+	for _, column := range LeadColumns {
+		if column.Default {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func LookupLeadColumn(name string) (LeadColumn, bool) {
+	for _, column := range LeadColumns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return LeadColumn{}, false
+}
+
+// Colunas a partir de uma lista separada por vírgula; vazia devolve as padrão
+func ParseLeadColumns(value string) ([]LeadColumn, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultLeadColumns, nil
+	}
+	var columns []LeadColumn
+	for _, name := range strings.Split(value, ",") {
+		column, ok := LookupLeadColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown lead column %q", strings.TrimSpace(name))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Cabeçalho no idioma pedido; sem idioma (ou sem tradução) usa o nome da coluna
+func (c LeadColumn) Header(lang string) string {
+	if label, ok := c.Labels[lang]; ok {
+		return label
+	}
+	return c.Name
+}
+
+// Valor da coluna como texto, no mesmo formato do dump
+func (c LeadColumn) Format(lead *Lead) string {
+	value := reflect.ValueOf(lead).Elem().FieldByName(c.Field).Interface()
+	switch v := value.(type) {
+	case sql.NullTime:
+		if !v.Valid {
+			return ""
+		}
+		return v.Time.Format("2006-01-02")
+	case uuid.UUID:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case interface{ Format(string) string }:
+		return v.Format(LeadTimeLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Valor da coluna com o tipo do campo, para JSON
+func (c LeadColumn) Value(lead *Lead) interface{} {
+	value := reflect.ValueOf(lead).Elem().FieldByName(c.Field).Interface()
+	if v, ok := value.(sql.NullTime); ok {
+		if !v.Valid {
+			return nil
+		}
+		return v.Time.Format("2006-01-02")
+	}
+	return value
+}
+
+// Preenche o campo da coluna a partir do texto. NULL e vazio deixam o campo
+// com o valor zero.
+func (c LeadColumn) Parse(lead *Lead, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "NULL") {
+		return nil
+	}
+
+	field := reflect.ValueOf(lead).Elem().FieldByName(c.Field)
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(text)
+	case uuid.UUID:
+		id, err := uuid.Parse(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid UUID %q", c.Name, text)
+		}
+		field.Set(reflect.ValueOf(id))
+	case bool:
+		value, err := strconv.ParseBool(strings.ToLower(text))
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q", c.Name, text)
+		}
+		field.SetBool(value)
+	case int:
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", c.Name, text)
+		}
+		field.SetInt(int64(value))
+	case float64:
+		value, err := strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", c.Name, text)
+		}
+		field.SetFloat(value)
+	case sql.NullTime:
+		value, err := parseLeadTime(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid date %q", c.Name, text)
+		}
+		field.Set(reflect.ValueOf(sql.NullTime{Time: value, Valid: true}))
+	case time.Time:
+		value, err := parseLeadTime(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid timestamp %q", c.Name, text)
+		}
+		field.Set(reflect.ValueOf(value))
+	default:
+		return fmt.Errorf("%s: column cannot be imported", c.Name)
+	}
+	return nil
+}
+
+var leadTimeLayouts = []string{LeadTimeLayout, time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02", "02/01/2006"}
+
+func parseLeadTime(text string) (time.Time, error) {
+	var err error
+	for _, layout := range leadTimeLayouts {
+		var value time.Time
+		if value, err = time.Parse(layout, text); err == nil {
+			return value, nil
+		}
+	}
+	return time.Time{}, err
+}
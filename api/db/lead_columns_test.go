@@ -0,0 +1,117 @@
+package db
+
+import (
+	"encoding/csv"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func readLeadDump(t *testing.T) [][]string {
+	t.Helper()
+	file, err := os.Open("../../data-021024.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read data-021024.csv: %v", err)
+	}
+	return records
+}
+
+func TestDefaultLeadColumnsMatchDump(t *testing.T) {
+	header := readLeadDump(t)[0]
+	if len(DefaultLeadColumns) != len(header) {
+		t.Fatalf("expected %d default columns, got %d", len(header), len(DefaultLeadColumns))
+	}
+	for i, column := range DefaultLeadColumns {
+		if column.Name != header[i] {
+			t.Errorf("column %d: expected %s, got %s", i, header[i], column.Name)
+		}
+	}
+}
+
+// Os valores saem no formato do dump
+func TestLeadColumnFormatMatchesDump(t *testing.T) {
+	records := readLeadDump(t)
+	header, record := records[0], records[1]
+	lead := Lead{
+		ID:               uuid.MustParse("00504201-35d6-445f-9765-4a77f3416d91"),
+		BusinessName:     "Restaurante Dona Florinda",
+		Rating:           4.4,
+		PriceLevel:       3,
+		UserRatingsTotal: 925,
+		BusinessStatus:   "OPERATIONAL",
+		CreatedAt:        time.Date(2024, 9, 27, 17, 5, 2, 205164000, time.UTC),
+	}
+
+	for i, name := range header {
+		switch name {
+		case "id", "business_name", "rating", "price_level", "user_ratings_total", "business_status", "created_at":
+			column, _ := LookupLeadColumn(name)
+			if got := column.Format(&lead); got != record[i] {
+				t.Errorf("%s: expected %q, got %q", name, record[i], got)
+			}
+		}
+	}
+	if permanentlyClosed, _ := LookupLeadColumn("permanently_closed"); permanentlyClosed.Format(&lead) != "false" {
+		t.Errorf("expected permanently_closed false, got %q", permanentlyClosed.Format(&lead))
+	}
+	if foundationDate, _ := LookupLeadColumn("foundation_date"); foundationDate.Format(&lead) != "" {
+		t.Errorf("expected an empty foundation_date, got %q", foundationDate.Format(&lead))
+	}
+}
+
+// Cada linha do dump lida com Parse e escrita com Format volta ao mesmo lead
+func TestLeadColumnsRoundTrip(t *testing.T) {
+	records := readLeadDump(t)
+	for line, record := range records[1:] {
+		var lead Lead
+		for i, column := range DefaultLeadColumns {
+			if err := column.Parse(&lead, record[i]); err != nil {
+				t.Fatalf("line %d: %v", line+2, err)
+			}
+		}
+
+		var parsed Lead
+		for _, column := range DefaultLeadColumns {
+			if err := column.Parse(&parsed, column.Format(&lead)); err != nil {
+				t.Fatalf("line %d: %v", line+2, err)
+			}
+		}
+		for _, column := range DefaultLeadColumns {
+			if column.Format(&parsed) != column.Format(&lead) {
+				t.Errorf("line %d: %s changed from %q to %q", line+2, column.Name, column.Format(&lead), column.Format(&parsed))
+			}
+		}
+		if !reflect.DeepEqual(lead, parsed) {
+			t.Errorf("line %d: expected %+v, got %+v", line+2, lead, parsed)
+		}
+	}
+}
+
+func TestLeadColumnParseErrors(t *testing.T) {
+	tests := []struct {
+		column string
+		text   string
+	}{
+		{column: "id", text: "lead-1"},
+		{column: "rating", text: "quatro"},
+		{column: "user_ratings_total", text: "4.5"},
+		{column: "permanently_closed", text: "talvez"},
+		{column: "created_at", text: "ontem"},
+	}
+	for _, tt := range tests {
+		column, _ := LookupLeadColumn(tt.column)
+		var lead Lead
+		if err := column.Parse(&lead, tt.text); err == nil {
+			t.Errorf("%s: expected an error for %q", tt.column, tt.text)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Origem de uma alteração, gravada em lead_field_history.source
+const (
+	SourceGooglePlaces = "GooglePlaces"
+	SourceCNPJBiz      = "cnpj.biz"
+	SourceScrapper     = "scrapper"
+	SourceManual       = "manual"
+)
+
+// Valor anterior e novo de um campo do lead a cada atualização
+type LeadFieldHistory struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	LeadID    uuid.UUID `gorm:"type:uuid;index" json:"lead_id"`
+	Field     string    `gorm:"type:text" json:"field"`
+	OldValue  string    `gorm:"type:text" json:"old_value"`
+	NewValue  string    `gorm:"type:text" json:"new_value"`
+	Source    string    `gorm:"type:text" json:"source"`
+	ChangedAt time.Time `gorm:"autoCreateTime;index" json:"changed_at"`
+}
+
+func (LeadFieldHistory) TableName() string {
+	return "lead_field_history"
+}
+
+// Campos que não entram no histórico
+var leadHistoryIgnoredFields = map[string]bool{
+	"ID":           true,
+	"LeadSteps":    true,
+	"Phones":       true,
+	"CreatedAt":    true,
+	"UpdatedAt":    true,
+	"ChangeSource": true,
+	"ScoredAt":     true,
+}
+
+// Compara o lead com o registro salvo antes de cada update e grava os campos
+// alterados. Roda na mesma transação do update.
+func (lead *Lead) BeforeUpdate(tx *gorm.DB) error {
+	if lead.ID == uuid.Nil {
+		return nil
+	}
+
+	session := tx.Session(&gorm.Session{NewDB: true})
+	var previous Lead
+	result := session.Where("id = ?", lead.ID).Limit(1).Find(&previous)
+	if result.Error != nil {
+		return fmt.Errorf("Erro ao buscar o lead para o histórico: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	source := lead.ChangeSource
+	if source == "" {
+		source = SourceManual
+	}
+
+	history := diffLeadFields(&previous, lead, selectedFields(tx))
+	for i := range history {
+		history[i].Source = source
+	}
+	if len(history) == 0 {
+		return nil
+	}
+
+	if err := session.Create(&history).Error; err != nil {
+		return fmt.Errorf("Erro ao gravar o histórico do lead: %v", err)
+	}
+	return nil
+}
+
+// Campos passados em Select(...); vazio quando o update grava todos
+func selectedFields(tx *gorm.DB) map[string]bool {
+	if len(tx.Statement.Selects) == 0 {
+		return nil
+	}
+	selected := make(map[string]bool)
+	for _, name := range tx.Statement.Selects {
+		if name == "*" {
+			return nil
+		}
+		if field := tx.Statement.Schema.LookUpField(name); field != nil {
+			selected[field.Name] = true
+		}
+	}
+	return selected
+}
+
+// Campos com valor diferente entre o registro salvo e o lead alterado
+func LeadChanges(previous *Lead, current *Lead) []string {
+	var changed []string
+	for _, history := range diffLeadFields(previous, current, nil) {
+		changed = append(changed, history.Field)
+	}
+	return changed
+}
+
+func diffLeadFields(previous *Lead, current *Lead, selected map[string]bool) []LeadFieldHistory {
+	var history []LeadFieldHistory
+	previousValue := reflect.ValueOf(previous).Elem()
+	currentValue := reflect.ValueOf(current).Elem()
+	leadType := previousValue.Type()
+
+	for i := 0; i < leadType.NumField(); i++ {
+		name := leadType.Field(i).Name
+		if leadHistoryIgnoredFields[name] || (selected != nil && !selected[name]) {
+			continue
+		}
+
+		oldValue := historyValue(previousValue.Field(i))
+		newValue := historyValue(currentValue.Field(i))
+		if oldValue == newValue {
+			continue
+		}
+		history = append(history, LeadFieldHistory{
+			LeadID:   current.ID,
+			Field:    name,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+	return history
+}
+
+func historyValue(value reflect.Value) string {
+	if nullTime, ok := value.Interface().(sql.NullTime); ok {
+		if !nullTime.Valid {
+			return ""
+		}
+		return nullTime.Time.Format("2006-01-02")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// Histórico do lead, mais recente primeiro. field vazio traz todos os campos.
+func GetLeadFieldHistory(leadID uuid.UUID, field string, limit int) ([]LeadFieldHistory, error) {
+	var history []LeadFieldHistory
+	query := DB.Where("lead_id = ?", leadID)
+	if field != "" {
+		query = query.Where("field = ?", field)
+	}
+	result := query.Order("changed_at DESC").Limit(limit).Find(&history)
+	if result.Error != nil {
+		return nil, fmt.Errorf("Erro ao buscar o histórico do lead: %v", result.Error)
+	}
+	return history, nil
+}
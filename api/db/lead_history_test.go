@@ -0,0 +1,27 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffLeadFieldsOnlySelected(t *testing.T) {
+	previous := Lead{ID: uuid.New(), City: "São Paulo"}
+	current := previous
+	current.Phone = "+551134567890"
+	current.Email = "contato@padariacentral.com.br"
+	current.Owner = "Maria Souza"
+	current.City = "Campinas"
+
+	selected := map[string]bool{"Phone": true, "Email": true, "Owner": true}
+	history := diffLeadFields(&previous, &current, selected)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history rows, got %+v", history)
+	}
+	for _, row := range history {
+		if !selected[row.Field] || row.LeadID != previous.ID || row.OldValue != "" || row.NewValue == "" {
+			t.Errorf("unexpected history row %+v", row)
+		}
+	}
+}
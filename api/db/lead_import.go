@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Regras para combinar um lead importado com um já existente: a importação
+// completa o que falta, mas não sobrescreve dados já coletados
+var ImportMergeRules = importMergeRules()
+
+func importMergeRules() []LeadMergeRule {
+	var rules []LeadMergeRule
+	for _, column := range LeadColumns {
+		switch column.Field {
+		case "ID", "CreatedAt", "UpdatedAt", "FieldsFilled", "QualityScore":
+			continue
+		case "Categories", "Types":
+			rules = append(rules, LeadMergeRule{Field: column.Field, Policy: MergeAppendUnique, Separator: ", "})
+		case "Description", "Address":
+			rules = append(rules, LeadMergeRule{Field: column.Field, Policy: MergePreferLonger})
+		default:
+			rules = append(rules, LeadMergeRule{Field: column.Field, Policy: MergeKeepIfPresent})
+		}
+	}
+	return rules
+}
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// Só os dígitos do CNPJ
+func NormalizeCNPJ(cnpj string) string {
+	return nonDigits.ReplaceAllString(cnpj, "")
+}
+
+// Procura um lead existente com o mesmo id, google_id, CNPJ ou algum dos
+// telefones em lead.Phones, nessa ordem. Devolve o lead e a chave que casou,
+// ou nil quando não há duplicado.
+func FindDuplicateLead(lead *Lead) (*Lead, string, error) {
+	candidates := []struct {
+		key   string
+		query func() *gorm.DB
+		ok    bool
+	}{
+		{"id", func() *gorm.DB { return DB.Where("id = ?", lead.ID) }, lead.ID != uuid.Nil},
+		{"google_id", func() *gorm.DB { return DB.Where("google_id = ?", lead.GoogleId) }, lead.GoogleId != ""},
+		{"cnpj", func() *gorm.DB {
+			return DB.Where("regexp_replace(company_registration_id, '\\D', '', 'g') = ?", NormalizeCNPJ(lead.CompanyRegistrationID))
+		}, NormalizeCNPJ(lead.CompanyRegistrationID) != ""},
+		{"phone", func() *gorm.DB {
+			return DB.Where("id IN (SELECT lead_id FROM lead_phones WHERE number IN ?)", leadPhoneNumbers(lead))
+		}, len(lead.Phones) > 0},
+	}
+
+	for _, candidate := range candidates {
+		if !candidate.ok {
+			continue
+		}
+		var existing Lead
+		result := candidate.query().Order("created_at").First(&existing)
+		if result.Error == nil {
+			return &existing, candidate.key, nil
+		}
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("Erro ao buscar lead duplicado por %s: %v", candidate.key, result.Error)
+		}
+	}
+	return nil, "", nil
+}
+
+func leadPhoneNumbers(lead *Lead) []string {
+	numbers := make([]string, 0, len(lead.Phones))
+	for _, leadPhone := range lead.Phones {
+		numbers = append(numbers, leadPhone.Number)
+	}
+	return numbers
+}
+
+// Cria um lead importado com os telefones em lead.Phones e o passo correspondente
+func CreateImportedLead(lead *Lead) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(lead).Error; err != nil {
+			return fmt.Errorf("Failed to save lead to database: %v", err)
+		}
+		return tx.Create(&LeadStep{
+			LeadID:  lead.ID,
+			Step:    "Lead Importado",
+			Status:  "Sucesso",
+			Details: fmt.Sprintf("Lead %s importado de %s", lead.BusinessName, lead.Source),
+		}).Error
+	})
+}
+
+// Grava os campos alterados de um lead existente, com o passo correspondente
+func SaveLeadChanges(lead *Lead, changed []string, step string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(lead).Select(changed).Updates(lead).Error; err != nil {
+			return fmt.Errorf("Erro ao atualizar o lead: %v", err)
+		}
+		return tx.Create(&LeadStep{
+			LeadID:  lead.ID,
+			Step:    step,
+			Status:  "Sucesso",
+			Details: fmt.Sprintf("Campos alterados: %s", strings.Join(changed, ", ")),
+		}).Error
+	})
+}
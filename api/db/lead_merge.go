@@ -0,0 +1,272 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Como um campo recebido é combinado com o valor já salvo no lead
+type MergePolicy int
+
+const (
+	// O valor recebido substitui o salvo, desde que não esteja vazio.
+	// Campos bool são sempre substituídos.
+	MergeOverwrite MergePolicy = iota
+	// O valor recebido só é usado quando o salvo está vazio
+	MergeKeepIfPresent
+	// Lista separada por Separator: acrescenta os itens que ainda não existem
+	MergeAppendUnique
+	// Fica o texto mais longo entre o salvo e o recebido
+	MergePreferLonger
+)
+
+type LeadMergeRule struct {
+	Field     string // nome do campo em Lead
+	Policy    MergePolicy
+	Separator string // só para MergeAppendUnique
+}
+
+// Regras para os leads vindos do Google Places. Campos fora da lista (dados
+// de CNPJ, qualidade etc.) nunca são alterados pelo upsert.
+var GooglePlacesMergeRules = []LeadMergeRule{
+	{Field: "BusinessName", Policy: MergeOverwrite},
+	{Field: "Address", Policy: MergePreferLonger},
+	{Field: "City", Policy: MergeKeepIfPresent},
+	{Field: "State", Policy: MergeKeepIfPresent},
+	{Field: "ZIPCode", Policy: MergeKeepIfPresent},
+	{Field: "Country", Policy: MergeKeepIfPresent},
+	{Field: "Phone", Policy: MergeOverwrite},
+	{Field: "Whatsapp", Policy: MergeOverwrite},
+	{Field: "Email", Policy: MergeKeepIfPresent},
+	{Field: "Website", Policy: MergeOverwrite},
+	{Field: "Instagram", Policy: MergeKeepIfPresent},
+	{Field: "Facebook", Policy: MergeKeepIfPresent},
+	{Field: "Description", Policy: MergePreferLonger},
+	{Field: "Rating", Policy: MergeOverwrite},
+	{Field: "UserRatingsTotal", Policy: MergeOverwrite},
+	{Field: "PriceLevel", Policy: MergeOverwrite},
+	{Field: "BusinessStatus", Policy: MergeOverwrite},
+	{Field: "Vicinity", Policy: MergeOverwrite},
+	{Field: "PermanentlyClosed", Policy: MergeOverwrite},
+	{Field: "Categories", Policy: MergeAppendUnique, Separator: ", "},
+	{Field: "OpeningHours", Policy: MergeOverwrite},
+	{Field: "SearchTerm", Policy: MergeKeepIfPresent},
+	{Field: "Source", Policy: MergeKeepIfPresent},
+}
+
+// Regras para combinar leads duplicados no mesmo lead: o valor já salvo
+// prevalece e os vazios são preenchidos, com listas somadas e o texto mais
+// longo mantido
+var DuplicateMergeRules = []LeadMergeRule{
+	{Field: "BusinessName", Policy: MergeKeepIfPresent},
+	{Field: "RegisteredName", Policy: MergeKeepIfPresent},
+	{Field: "FoundationDate", Policy: MergeKeepIfPresent},
+	{Field: "Address", Policy: MergePreferLonger},
+	{Field: "City", Policy: MergeKeepIfPresent},
+	{Field: "State", Policy: MergeKeepIfPresent},
+	{Field: "Country", Policy: MergeKeepIfPresent},
+	{Field: "ZIPCode", Policy: MergeKeepIfPresent},
+	{Field: "Owner", Policy: MergeKeepIfPresent},
+	{Field: "Source", Policy: MergeKeepIfPresent},
+	{Field: "Phone", Policy: MergeKeepIfPresent},
+	{Field: "Whatsapp", Policy: MergeKeepIfPresent},
+	{Field: "Website", Policy: MergeKeepIfPresent},
+	{Field: "Email", Policy: MergeKeepIfPresent},
+	{Field: "Instagram", Policy: MergeKeepIfPresent},
+	{Field: "Facebook", Policy: MergeKeepIfPresent},
+	{Field: "TikTok", Policy: MergeKeepIfPresent},
+	{Field: "CompanyRegistrationID", Policy: MergeKeepIfPresent},
+	{Field: "Categories", Policy: MergeAppendUnique, Separator: ", "},
+	{Field: "Rating", Policy: MergeKeepIfPresent},
+	{Field: "PriceLevel", Policy: MergeKeepIfPresent},
+	{Field: "UserRatingsTotal", Policy: MergeKeepIfPresent},
+	{Field: "Vicinity", Policy: MergeKeepIfPresent},
+	{Field: "OpeningHours", Policy: MergeKeepIfPresent},
+	{Field: "CompanySize", Policy: MergeKeepIfPresent},
+	{Field: "Revenue", Policy: MergeKeepIfPresent},
+	{Field: "EmployeesCount", Policy: MergeKeepIfPresent},
+	{Field: "Description", Policy: MergePreferLonger},
+	{Field: "PrimaryActivity", Policy: MergeKeepIfPresent},
+	{Field: "SecondaryActivities", Policy: MergeKeepIfPresent},
+	{Field: "Types", Policy: MergeAppendUnique, Separator: ", "},
+	{Field: "EquityCapital", Policy: MergeKeepIfPresent},
+	{Field: "BusinessStatus", Policy: MergeKeepIfPresent},
+	{Field: "Quality", Policy: MergeKeepIfPresent},
+	{Field: "SearchTerm", Policy: MergeKeepIfPresent},
+}
+
+// Cria o lead ou, se o GoogleId já existe, combina os campos segundo as
+// regras. Os telefones em lead.Phones são gravados na mesma transação, e um
+// lead criado fica com o envio ao scrapper pendente (ver ScrapperPending).
+// Devolve se o lead foi criado e os campos alterados; ao final, lead contém o
+// registro salvo (com o ID do lead existente, quando for o caso).
+func UpsertLead(lead *Lead, rules []LeadMergeRule) (bool, []string, error) {
+	if lead.GoogleId == "" {
+		return false, nil, fmt.Errorf("Lead sem GoogleId não pode ser combinado")
+	}
+
+	phones := lead.Phones
+	lead.Phones = nil
+	created := false
+	var changed []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, changed, err = upsertLead(tx, lead, rules)
+		if err != nil {
+			return err
+		}
+		return saveLeadPhones(tx, lead.ID, phones)
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return created, changed, nil
+}
+
+func upsertLead(tx *gorm.DB, lead *Lead, rules []LeadMergeRule) (bool, []string, error) {
+	existing, err := lockLeadByGoogleId(tx, lead.GoogleId)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if existing == nil {
+		// O índice único em google_id resolve duas criações simultâneas:
+		// a segunda não insere nada e cai na combinação abaixo
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(lead)
+		if result.Error != nil {
+			return false, nil, fmt.Errorf("Failed to save lead to database: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			err := tx.Create(&[]LeadStep{
+				{
+					LeadID:  lead.ID,
+					Step:    "Lead Criado",
+					Status:  "Sucesso",
+					Details: fmt.Sprintf("Lead %s foi criado com sucesso", lead.BusinessName),
+				},
+				{
+					LeadID:  lead.ID,
+					Step:    StepScrapperPending,
+					Status:  "Pendente",
+					Details: fmt.Sprintf("Lead %s aguardando envio ao scrapper", lead.BusinessName),
+				},
+			}).Error
+			return true, nil, err
+		}
+
+		existing, err = lockLeadByGoogleId(tx, lead.GoogleId)
+		if err != nil {
+			return false, nil, err
+		}
+		if existing == nil {
+			return false, nil, fmt.Errorf("Lead com GoogleId %s não encontrado após conflito", lead.GoogleId)
+		}
+	}
+
+	changed := MergeLead(existing, lead, rules)
+	existing.ChangeSource = lead.ChangeSource
+	if existing.ChangeSource == "" {
+		existing.ChangeSource = lead.Source
+	}
+	*lead = *existing
+	if len(changed) == 0 {
+		return false, changed, nil
+	}
+
+	result := tx.Model(existing).Select(changed).Updates(existing)
+	if result.Error != nil {
+		return false, nil, fmt.Errorf("Erro ao atualizar o lead: %v", result.Error)
+	}
+	err = tx.Create(&LeadStep{
+		LeadID:  existing.ID,
+		Step:    "Lead Atualizado",
+		Status:  "Sucesso",
+		Details: fmt.Sprintf("Campos alterados: %s", strings.Join(changed, ", ")),
+	}).Error
+	return false, changed, err
+}
+
+func lockLeadByGoogleId(tx *gorm.DB, googleId string) (*Lead, error) {
+	var lead Lead
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("google_id = ?", googleId).First(&lead)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Erro ao buscar o lead: %v", result.Error)
+	}
+	return &lead, nil
+}
+
+// Aplica em target os campos de incoming segundo as regras e devolve os
+// nomes dos campos que mudaram
+func MergeLead(target *Lead, incoming *Lead, rules []LeadMergeRule) []string {
+	var changed []string
+	targetValue := reflect.ValueOf(target).Elem()
+	incomingValue := reflect.ValueOf(incoming).Elem()
+
+	for _, rule := range rules {
+		current := targetValue.FieldByName(rule.Field)
+		next := incomingValue.FieldByName(rule.Field)
+		if !current.IsValid() || !next.IsValid() {
+			continue
+		}
+
+		merged, ok := mergeField(rule, current, next)
+		if !ok || reflect.DeepEqual(current.Interface(), merged.Interface()) {
+			continue
+		}
+		current.Set(merged)
+		changed = append(changed, rule.Field)
+	}
+	return changed
+}
+
+// O bool indica se há um valor para gravar
+func mergeField(rule LeadMergeRule, current reflect.Value, next reflect.Value) (reflect.Value, bool) {
+	switch rule.Policy {
+	case MergeOverwrite:
+		if next.Kind() == reflect.Bool || !next.IsZero() {
+			return next, true
+		}
+	case MergeKeepIfPresent:
+		if current.IsZero() && !next.IsZero() {
+			return next, true
+		}
+	case MergeAppendUnique:
+		if current.Kind() == reflect.String && !next.IsZero() {
+			return reflect.ValueOf(appendUnique(current.String(), next.String(), rule.Separator)), true
+		}
+	case MergePreferLonger:
+		if current.Kind() == reflect.String && len(next.String()) > len(current.String()) {
+			return next, true
+		}
+	}
+	return current, false
+}
+
+func appendUnique(current string, next string, separator string) string {
+	if separator == "" {
+		separator = ", "
+	}
+	trimmed := strings.TrimSpace(separator)
+
+	var items []string
+	seen := make(map[string]bool)
+	for _, value := range []string{current, next} {
+		for _, item := range strings.Split(value, trimmed) {
+			item = strings.TrimSpace(item)
+			if item == "" || seen[item] {
+				continue
+			}
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, separator)
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeLeadPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     LeadMergeRule
+		target   Lead
+		incoming Lead
+		want     Lead
+		changed  []string
+	}{
+		{
+			name:     "overwrite replaces with incoming value",
+			rule:     LeadMergeRule{Field: "Phone", Policy: MergeOverwrite},
+			target:   Lead{Phone: "+551134567890"},
+			incoming: Lead{Phone: "+551140041234"},
+			want:     Lead{Phone: "+551140041234"},
+			changed:  []string{"Phone"},
+		},
+		{
+			name:     "overwrite ignores empty incoming value",
+			rule:     LeadMergeRule{Field: "Phone", Policy: MergeOverwrite},
+			target:   Lead{Phone: "+551134567890"},
+			incoming: Lead{},
+			want:     Lead{Phone: "+551134567890"},
+		},
+		{
+			name:     "overwrite always replaces bools",
+			rule:     LeadMergeRule{Field: "PermanentlyClosed", Policy: MergeOverwrite},
+			target:   Lead{PermanentlyClosed: true},
+			incoming: Lead{},
+			want:     Lead{},
+			changed:  []string{"PermanentlyClosed"},
+		},
+		{
+			name:     "overwrite replaces numbers",
+			rule:     LeadMergeRule{Field: "Rating", Policy: MergeOverwrite},
+			target:   Lead{Rating: 4.2},
+			incoming: Lead{Rating: 4.7},
+			want:     Lead{Rating: 4.7},
+			changed:  []string{"Rating"},
+		},
+		{
+			name:     "keep if present fills empty value",
+			rule:     LeadMergeRule{Field: "Email", Policy: MergeKeepIfPresent},
+			target:   Lead{},
+			incoming: Lead{Email: "contato@padaria.com.br"},
+			want:     Lead{Email: "contato@padaria.com.br"},
+			changed:  []string{"Email"},
+		},
+		{
+			name:     "keep if present keeps saved value",
+			rule:     LeadMergeRule{Field: "Email", Policy: MergeKeepIfPresent},
+			target:   Lead{Email: "vendas@padaria.com.br"},
+			incoming: Lead{Email: "contato@padaria.com.br"},
+			want:     Lead{Email: "vendas@padaria.com.br"},
+		},
+		{
+			name:     "append unique adds new items only",
+			rule:     LeadMergeRule{Field: "Categories", Policy: MergeAppendUnique, Separator: ", "},
+			target:   Lead{Categories: "bakery, food"},
+			incoming: Lead{Categories: "food, store"},
+			want:     Lead{Categories: "bakery, food, store"},
+			changed:  []string{"Categories"},
+		},
+		{
+			name:     "append unique without new items",
+			rule:     LeadMergeRule{Field: "Categories", Policy: MergeAppendUnique, Separator: ", "},
+			target:   Lead{Categories: "bakery, food"},
+			incoming: Lead{Categories: "food"},
+			want:     Lead{Categories: "bakery, food"},
+		},
+		{
+			name:     "prefer longer takes longer text",
+			rule:     LeadMergeRule{Field: "Address", Policy: MergePreferLonger},
+			target:   Lead{Address: "Rua Augusta, 100"},
+			incoming: Lead{Address: "Rua Augusta, 100 - Consolação"},
+			want:     Lead{Address: "Rua Augusta, 100 - Consolação"},
+			changed:  []string{"Address"},
+		},
+		{
+			name:     "prefer longer keeps longer saved text",
+			rule:     LeadMergeRule{Field: "Address", Policy: MergePreferLonger},
+			target:   Lead{Address: "Rua Augusta, 100 - Consolação"},
+			incoming: Lead{Address: "Rua Augusta"},
+			want:     Lead{Address: "Rua Augusta, 100 - Consolação"},
+		},
+		{
+			name:     "unknown field is ignored",
+			rule:     LeadMergeRule{Field: "Unknown", Policy: MergeOverwrite},
+			target:   Lead{Phone: "+551134567890"},
+			incoming: Lead{Phone: "+551140041234"},
+			want:     Lead{Phone: "+551134567890"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			changed := MergeLead(&target, &tt.incoming, []LeadMergeRule{tt.rule})
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("expected changed fields %v, got %v", tt.changed, changed)
+			}
+			if !reflect.DeepEqual(target, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, target)
+			}
+		})
+	}
+}
+
+// Campos fora das regras do Google Places, como os dados de CNPJ, não mudam
+func TestMergeLeadGooglePlacesKeepsCNPJData(t *testing.T) {
+	target := Lead{CompanyRegistrationID: "12.345.678/0001-90", Owner: "Maria Souza", BusinessName: "Padaria"}
+	incoming := Lead{BusinessName: "Padaria Central", Owner: "Outro"}
+
+	changed := MergeLead(&target, &incoming, GooglePlacesMergeRules)
+	if !reflect.DeepEqual(changed, []string{"BusinessName"}) {
+		t.Errorf("expected only BusinessName to change, got %v", changed)
+	}
+	if target.Owner != "Maria Souza" || target.CompanyRegistrationID != "12.345.678/0001-90" {
+		t.Errorf("unexpected CNPJ data after merge: %+v", target)
+	}
+}
+
+// Os duplicados só preenchem o que falta no lead mantido
+func TestMergeLeadDuplicateRules(t *testing.T) {
+	kept := Lead{BusinessName: "Padaria Central", Phone: "+551134567890", Categories: "bakery", Address: "Rua Augusta, 100"}
+	duplicate := Lead{BusinessName: "Padaria", Phone: "+551140041234", Email: "contato@padaria.com.br", Categories: "food", Address: "Rua Augusta, 100 - Consolação"}
+
+	changed := MergeLead(&kept, &duplicate, DuplicateMergeRules)
+	if !reflect.DeepEqual(changed, []string{"Address", "Email", "Categories"}) {
+		t.Errorf("unexpected changed fields %v", changed)
+	}
+	expected := Lead{BusinessName: "Padaria Central", Phone: "+551134567890", Email: "contato@padaria.com.br", Categories: "bakery, food", Address: "Rua Augusta, 100 - Consolação"}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("expected %+v, got %+v", expected, kept)
+	}
+}
+
+func TestMergeRulesReferToLeadFields(t *testing.T) {
+	leadType := reflect.TypeOf(Lead{})
+	for _, rules := range [][]LeadMergeRule{GooglePlacesMergeRules, DuplicateMergeRules} {
+		for _, rule := range rules {
+			if _, ok := leadType.FieldByName(rule.Field); !ok {
+				t.Errorf("merge rule refers to unknown field %s", rule.Field)
+			}
+		}
+	}
+}
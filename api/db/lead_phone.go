@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"shared/phone"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Telefone de um lead em E.164. Lead.Phone e Lead.Whatsapp guardam só o
+// número principal; a lista completa fica aqui.
+type LeadPhone struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	LeadID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_lead_phones_lead_number" json:"lead_id"`
+	Number    string    `gorm:"type:text;uniqueIndex:idx_lead_phones_lead_number;index" json:"number"`
+	Kind      string    `gorm:"size:10" json:"kind"` // mobile ou fixed
+	Whatsapp  bool      `gorm:"default:false" json:"whatsapp"`
+	Source    string    `gorm:"type:text" json:"source"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func NewLeadPhone(number phone.Number, source string) LeadPhone {
+	return LeadPhone{Number: number.E164(), Kind: string(number.Kind), Source: source}
+}
+
+// Adiciona os telefones ao lead. Um número já salvo não é duplicado, mas
+// passa a constar como WhatsApp se a nova verificação confirmar.
+func SaveLeadPhones(leadID uuid.UUID, phones []LeadPhone) error {
+	return saveLeadPhones(DB, leadID, phones)
+}
+
+func saveLeadPhones(tx *gorm.DB, leadID uuid.UUID, phones []LeadPhone) error {
+	// Um mesmo número duas vezes no lote quebraria o ON CONFLICT
+	var unique []LeadPhone
+	index := make(map[string]int)
+	for _, leadPhone := range phones {
+		leadPhone.LeadID = leadID
+		if i, ok := index[leadPhone.Number]; ok {
+			unique[i].Whatsapp = unique[i].Whatsapp || leadPhone.Whatsapp
+			continue
+		}
+		index[leadPhone.Number] = len(unique)
+		unique = append(unique, leadPhone)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "lead_id"}, {Name: "number"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"whatsapp": gorm.Expr("lead_phones.whatsapp OR excluded.whatsapp"),
+		}),
+	}).Create(&unique)
+	if result.Error != nil {
+		return fmt.Errorf("Erro ao salvar telefones do lead %s: %v", leadID, result.Error)
+	}
+	return nil
+}
+
+func GetLeadPhones(leadID uuid.UUID) ([]LeadPhone, error) {
+	var phones []LeadPhone
+	result := DB.Where("lead_id = ?", leadID).Order("created_at").Find(&phones)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return phones, nil
+}
+
+// Preenche lead_phones a partir das colunas phone e whatsapp dos leads
+// antigos, que podiam ter vários números separados por vírgula
+func backfillLeadPhones() error {
+	type legacyPhones struct {
+		ID       uuid.UUID
+		Phone    string
+		Whatsapp string
+	}
+
+	var leads []legacyPhones
+	result := DB.Raw(`
+		SELECT id, COALESCE(phone, '') AS phone, COALESCE(whatsapp, '') AS whatsapp FROM leads
+		WHERE (COALESCE(phone, '') <> '' OR COALESCE(whatsapp, '') <> '')
+		AND NOT EXISTS (SELECT 1 FROM lead_phones WHERE lead_phones.lead_id = leads.id)
+	`).Scan(&leads)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, lead := range leads {
+		whatsapp := make(map[string]bool)
+		numbers, _ := phone.ParseAll(lead.Whatsapp)
+		for _, number := range numbers {
+			whatsapp[number.E164()] = true
+		}
+		phoneNumbers, _ := phone.ParseAll(lead.Phone)
+		numbers = append(phoneNumbers, numbers...)
+
+		var phones []LeadPhone
+		for _, number := range numbers {
+			leadPhone := NewLeadPhone(number, "legacy")
+			leadPhone.Whatsapp = whatsapp[number.E164()]
+			phones = append(phones, leadPhone)
+		}
+		if err := SaveLeadPhones(lead.ID, phones); err != nil {
+			return err
+		}
+	}
+	if len(leads) > 0 {
+		log.Printf("Telefones de %d leads copiados para lead_phones", len(leads))
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Filtros da listagem de leads; campos vazios ou nil não filtram
+type LeadFilter struct {
+	City           string
+	State          string
+	Category       string // procura em Categories e Types
+	Quality        string
+	BusinessStatus string
+	SearchTerm     string
+	HasPhone       *bool
+	HasWhatsapp    *bool
+	HasEmail       *bool
+	MinRating      *float64
+	MaxRating      *float64
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time // exclusivo
+}
+
+// Colunas aceitas na ordenação. O id desempata e faz parte do cursor.
+var leadSortColumns = map[string]string{
+	"created_at":         "time",
+	"updated_at":         "time",
+	"rating":             "number",
+	"user_ratings_total": "number",
+	"fields_filled":      "number",
+	"quality_score":      "number",
+	"business_name":      "text",
+}
+
+type LeadSort struct {
+	Column string
+	Desc   bool
+}
+
+var DefaultLeadSort = LeadSort{Column: "created_at", Desc: true}
+
+// Devolve a ordenação para valores como "rating" ou "-created_at"
+func ParseLeadSort(value string) (LeadSort, error) {
+	if value == "" {
+		return DefaultLeadSort, nil
+	}
+	sort := LeadSort{Column: strings.TrimPrefix(value, "-"), Desc: strings.HasPrefix(value, "-")}
+	if _, ok := leadSortColumns[sort.Column]; !ok {
+		return LeadSort{}, fmt.Errorf("invalid sort column %q", sort.Column)
+	}
+	return sort, nil
+}
+
+// Posição depois do último lead de uma página
+type LeadCursor struct {
+	Value interface{} `json:"v"`
+	ID    uuid.UUID   `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c LeadCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeLeadCursor(value string, sort LeadSort) (*LeadCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor LeadCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// O JSON perde o tipo do valor; volta para o tipo da coluna
+	switch leadSortColumns[sort.Column] {
+	case "time":
+		text, _ := cursor.Value.(string)
+		parsed, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		cursor.Value = parsed
+	case "number":
+		if _, ok := cursor.Value.(float64); !ok {
+			return nil, ErrInvalidCursor
+		}
+	case "text":
+		if _, ok := cursor.Value.(string); !ok {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
+
+func leadCursorFor(lead *Lead, sort LeadSort) LeadCursor {
+	cursor := LeadCursor{ID: lead.ID}
+	switch sort.Column {
+	case "created_at":
+		cursor.Value = lead.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = lead.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "rating":
+		cursor.Value = lead.Rating
+	case "user_ratings_total":
+		cursor.Value = float64(lead.UserRatingsTotal)
+	case "fields_filled":
+		cursor.Value = float64(lead.FieldsFilled)
+	case "quality_score":
+		cursor.Value = lead.QualityScore
+	case "business_name":
+		cursor.Value = lead.BusinessName
+	}
+	return cursor
+}
+
+// Aplica os filtros na consulta
+func (f LeadFilter) Apply(query *gorm.DB) *gorm.DB {
+	if f.City != "" {
+		query = query.Where("LOWER(city) = LOWER(?)", f.City)
+	}
+	if f.State != "" {
+		query = query.Where("UPPER(state) = UPPER(?)", f.State)
+	}
+	if f.Category != "" {
+		pattern := "%" + f.Category + "%"
+		query = query.Where("(categories ILIKE ? OR types ILIKE ?)", pattern, pattern)
+	}
+	if f.Quality != "" {
+		query = query.Where("quality = ?", f.Quality)
+	}
+	if f.BusinessStatus != "" {
+		query = query.Where("UPPER(business_status) = UPPER(?)", f.BusinessStatus)
+	}
+	if f.SearchTerm != "" {
+		query = query.Where("search_term ILIKE ?", "%"+f.SearchTerm+"%")
+	}
+	query = filterPresence(query, "phone", f.HasPhone)
+	query = filterPresence(query, "whatsapp", f.HasWhatsapp)
+	query = filterPresence(query, "email", f.HasEmail)
+	if f.MinRating != nil {
+		query = query.Where("rating >= ?", *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		query = query.Where("rating <= ?", *f.MaxRating)
+	}
+	if f.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		query = query.Where("created_at < ?", *f.CreatedTo)
+	}
+	return query
+}
+
+func filterPresence(query *gorm.DB, column string, present *bool) *gorm.DB {
+	if present == nil {
+		return query
+	}
+	if *present {
+		return query.Where(fmt.Sprintf("COALESCE(%s, '') <> ''", column))
+	}
+	return query.Where(fmt.Sprintf("COALESCE(%s, '') = ''", column))
+}
+
+// Consulta filtrada e ordenada, a partir do cursor quando houver
+func leadQuery(filter LeadFilter, sort LeadSort, cursor *LeadCursor) *gorm.DB {
+	direction := "ASC"
+	comparison := ">"
+	if sort.Desc {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	query := filter.Apply(DB.Model(&Lead{}))
+	if cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sort.Column, comparison), cursor.Value, cursor.ID)
+	}
+	return query.Order(fmt.Sprintf("%s %s, id %s", sort.Column, direction, direction))
+}
+
+// Uma página de leads. O cursor devolvido é nil na última página.
+func QueryLeads(filter LeadFilter, sort LeadSort, cursor *LeadCursor, limit int) ([]Lead, *LeadCursor, error) {
+	var leads []Lead
+	result := leadQuery(filter, sort, cursor).Limit(limit + 1).Find(&leads)
+	if result.Error != nil {
+		return nil, nil, fmt.Errorf("Erro ao buscar leads: %v", result.Error)
+	}
+
+	if len(leads) <= limit {
+		return leads, nil, nil
+	}
+	leads = leads[:limit]
+	next := leadCursorFor(&leads[limit-1], sort)
+	return leads, &next, nil
+}
+
+// Lead com os passos, em ordem cronológica, e os telefones. Devolve nil
+// quando não existe.
+func GetLeadWithSteps(leadID uuid.UUID) (*Lead, error) {
+	var lead Lead
+	result := DB.Preload("LeadSteps", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("timestamp ASC")
+	}).Preload("Phones", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at ASC")
+	}).First(&lead, "id = ?", leadID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &lead, nil
+}
+
+// Percorre todos os leads do filtro, um por vez, sem carregar a tabela em
+// memória. Para no primeiro erro devolvido por fn.
+func StreamLeads(filter LeadFilter, sort LeadSort, fn func(lead *Lead) error) error {
+	rows, err := leadQuery(filter, sort, nil).Rows()
+	if err != nil {
+		return fmt.Errorf("Erro ao buscar leads: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lead Lead
+		if err := DB.ScanRows(rows, &lead); err != nil {
+			return fmt.Errorf("Erro ao ler lead: %v", err)
+		}
+		if err := fn(&lead); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseLeadSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    LeadSort
+		invalid bool
+	}{
+		{value: "", want: DefaultLeadSort},
+		{value: "rating", want: LeadSort{Column: "rating"}},
+		{value: "-fields_filled", want: LeadSort{Column: "fields_filled", Desc: true}},
+		{value: "-quality_score", want: LeadSort{Column: "quality_score", Desc: true}},
+		{value: "business_name", want: LeadSort{Column: "business_name"}},
+		{value: "-", invalid: true},
+		{value: "email", invalid: true},
+		{value: "rating; DROP TABLE leads", invalid: true},
+	}
+
+	for _, tt := range tests {
+		sort, err := ParseLeadSort(tt.value)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("expected an error for %q, got %+v", tt.value, sort)
+			}
+			continue
+		}
+		if err != nil || sort != tt.want {
+			t.Errorf("expected %+v for %q, got %+v (err %v)", tt.want, tt.value, sort, err)
+		}
+	}
+}
+
+func TestLeadCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 10, 2, 13, 45, 12, 123456000, time.FixedZone("BRT", -3*60*60))
+	lead := &Lead{
+		ID:               uuid.New(),
+		CreatedAt:        createdAt,
+		Rating:           4.6,
+		UserRatingsTotal: 128,
+		FieldsFilled:     9,
+		QualityScore:     72.5,
+		BusinessName:     "Padaria Central",
+	}
+
+	tests := []struct {
+		column string
+		want   interface{}
+	}{
+		{column: "created_at", want: createdAt.UTC()},
+		{column: "rating", want: 4.6},
+		{column: "user_ratings_total", want: float64(128)},
+		{column: "fields_filled", want: float64(9)},
+		{column: "quality_score", want: 72.5},
+		{column: "business_name", want: "Padaria Central"},
+	}
+
+	for _, tt := range tests {
+		sort := LeadSort{Column: tt.column}
+		encoded := leadCursorFor(lead, sort).Encode()
+
+		cursor, err := DecodeLeadCursor(encoded, sort)
+		if err != nil {
+			t.Fatalf("%s: DecodeLeadCursor: %v", tt.column, err)
+		}
+		if cursor.ID != lead.ID {
+			t.Errorf("%s: expected id %s, got %s", tt.column, lead.ID, cursor.ID)
+		}
+		if value, ok := cursor.Value.(time.Time); ok {
+			if !value.Equal(tt.want.(time.Time)) {
+				t.Errorf("%s: expected %v, got %v", tt.column, tt.want, value)
+			}
+		} else if cursor.Value != tt.want {
+			t.Errorf("%s: expected %v (%T), got %v (%T)", tt.column, tt.want, tt.want, cursor.Value, cursor.Value)
+		}
+	}
+}
+
+func TestDecodeLeadCursorRejectsInvalidValues(t *testing.T) {
+	textCursor := LeadCursor{Value: "Padaria", ID: uuid.New()}.Encode()
+	numberCursor := LeadCursor{Value: 4.5, ID: uuid.New()}.Encode()
+
+	tests := []struct {
+		name  string
+		value string
+		sort  LeadSort
+	}{
+		{name: "not base64", value: "%%%", sort: DefaultLeadSort},
+		{name: "not json", value: base64.RawURLEncoding.EncodeToString([]byte("lead")), sort: DefaultLeadSort},
+		{name: "text for a time column", value: textCursor, sort: LeadSort{Column: "created_at"}},
+		{name: "text for a number column", value: textCursor, sort: LeadSort{Column: "rating"}},
+		{name: "number for a text column", value: numberCursor, sort: LeadSort{Column: "business_name"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := DecodeLeadCursor(tt.value, tt.sort); err != ErrInvalidCursor {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", tt.name, err)
+		}
+	}
+}
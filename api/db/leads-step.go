@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"time"
 	"github.com/google/uuid"
 )
@@ -34,4 +35,31 @@ func GetLeadSteps(leadID uuid.UUID) ([]LeadStep, error) {
 	return steps, nil
 }
 
+// Passos que marcam o envio de um lead novo ao scrapper
+const (
+	StepScrapperPending = "Aguardando Scrapper"
+	StepScrapperSent    = "Enviado ao Scrapper"
+)
 
+// Indica se o lead foi criado e ainda não foi enviado ao scrapper. Leads
+// anteriores a esses passos não têm nenhum dos dois e não são reenviados.
+func ScrapperPending(leadID uuid.UUID) (bool, error) {
+	var count int64
+	err := DB.Model(&LeadStep{}).
+		Where("lead_id = ? AND step = ?", leadID, StepScrapperPending).
+		Where("NOT EXISTS (SELECT 1 FROM lead_steps sent WHERE sent.lead_id = lead_steps.lead_id AND sent.step = ?)", StepScrapperSent).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("Erro ao consultar o envio do lead %s ao scrapper: %v", leadID, err)
+	}
+	return count > 0, nil
+}
+
+func MarkSentToScrapper(leadID uuid.UUID) error {
+	return CreateLeadStep(&LeadStep{
+		LeadID:  leadID,
+		Step:    StepScrapperSent,
+		Status:  "Sucesso",
+		Details: "Lead enviado ao scrapper",
+	})
+}
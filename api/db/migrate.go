@@ -2,6 +2,9 @@ package db
 
 import (
 		"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 
@@ -12,6 +15,13 @@ func Migrate() error {
         log.Fatalf("Falha ao criar a extensão uuid-ossp: %v", err)
     }
 
+	// O índice único em google_id não é criado se houver duplicados antigos
+	if DB.Migrator().HasTable(&Lead{}) && !DB.Migrator().HasIndex(&Lead{}, "idx_leads_google_id") {
+		if err := mergeDuplicatedLeads(); err != nil {
+			panic("Falha ao migrar banco de dados: " + err.Error())
+		}
+	}
+
 	err = DB.AutoMigrate(&Lead{})
 	if err != nil {
 		panic("Falha ao migrar banco de dados: " + err.Error())
@@ -21,5 +31,98 @@ func Migrate() error {
 	if err != nil {
 		panic("Falha ao migrar banco de dados: " + err.Error())
 	}
+
+	err = DB.AutoMigrate(&LeadPhone{})
+	if err != nil {
+		panic("Falha ao migrar banco de dados: " + err.Error())
+	}
+	if err := backfillLeadPhones(); err != nil {
+		panic("Falha ao migrar banco de dados: " + err.Error())
+	}
+
+	err = DB.AutoMigrate(&LeadFieldHistory{})
+	if err != nil {
+		panic("Falha ao migrar banco de dados: " + err.Error())
+	}
+
+	err = DB.AutoMigrate(&DeadLetter{})
+	if err != nil {
+		panic("Falha ao migrar banco de dados: " + err.Error())
+	}
+	return nil
+}
+
+// Mantém o lead mais antigo de cada google_id, combina nele os campos dos
+// duplicados, move para ele os passos, o histórico e os telefones e apaga os duplicados
+func mergeDuplicatedLeads() error {
+	var leads []Lead
+	err := DB.Where(`google_id IN (
+		SELECT google_id FROM leads
+		WHERE google_id IS NOT NULL AND google_id <> ''
+		GROUP BY google_id HAVING count(*) > 1
+	)`).Order("google_id, created_at, id").Find(&leads).Error
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for start := 0; start < len(leads); {
+		end := start + 1
+		for end < len(leads) && leads[end].GoogleId == leads[start].GoogleId {
+			end++
+		}
+		if err := mergeLeadGroup(&leads[start], leads[start+1:end]); err != nil {
+			return err
+		}
+		removed += end - start - 1
+		start = end
+	}
+	if removed > 0 {
+		log.Printf("%d leads duplicados por google_id combinados e removidos", removed)
+	}
 	return nil
 }
+
+func mergeLeadGroup(kept *Lead, duplicates []Lead) error {
+	var changed []string
+	ids := make([]uuid.UUID, 0, len(duplicates))
+	for i := range duplicates {
+		changed = append(changed, MergeLead(kept, &duplicates[i], DuplicateMergeRules)...)
+		ids = append(ids, duplicates[i].ID)
+	}
+
+	// Sem hooks: a tabela de histórico pode ainda não existir
+	return DB.Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		if len(changed) > 0 {
+			if err := tx.Model(kept).Select(changed).Updates(kept).Error; err != nil {
+				return err
+			}
+		}
+		if DB.Migrator().HasTable(&LeadStep{}) {
+			if err := tx.Exec(`UPDATE lead_steps SET lead_id = ? WHERE lead_id IN ?`, kept.ID, ids).Error; err != nil {
+				return err
+			}
+		}
+		if DB.Migrator().HasTable(&LeadPhone{}) {
+			// Números que o lead mantido já tem ficam com o duplicado e são apagados
+			for _, id := range ids {
+				err := tx.Exec(`
+					UPDATE lead_phones SET lead_id = ? WHERE lead_id = ?
+						AND number NOT IN (SELECT number FROM lead_phones WHERE lead_id = ?)
+				`, kept.ID, id, kept.ID).Error
+				if err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("lead_id IN ?", ids).Delete(&LeadPhone{}).Error; err != nil {
+				return err
+			}
+		}
+		if DB.Migrator().HasTable(&LeadFieldHistory{}) {
+			if err := tx.Exec(`UPDATE lead_field_history SET lead_id = ? WHERE lead_id IN ?`, kept.ID, ids).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id IN ?", ids).Delete(&Lead{}).Error
+	})
+}
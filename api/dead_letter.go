@@ -0,0 +1,415 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"api/db"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Retentativas de uma fila de consumo. Uma mensagem que falha é publicada no
+// exchange <Name>.retry e fica na fila <Name>.retry até o TTL vencer; aí o
+// RabbitMQ a devolve para a fila de origem pelo exchange padrão, registrando
+// a passagem no header x-death. Depois de MaxAttempts, ou num erro permanente,
+// ela vai para a tabela dead_letters, de onde pode ser reenviada pela API.
+type retryQueue struct {
+	Name        string // nome lógico, usado no exchange e na fila de retentativa
+	Queue       string // fila consumida (pode ser anônima)
+	Delay       time.Duration
+	MaxAttempts int
+}
+
+func newRetryQueue(name string, queue string) retryQueue {
+	return retryQueue{
+		Name:        name,
+		Queue:       queue,
+		Delay:       getEnvDuration("RETRY_DELAY", 30*time.Second),
+		MaxAttempts: getEnvInt("MAX_DELIVERY_ATTEMPTS", 5),
+	}
+}
+
+func (q retryQueue) retryName() string {
+	return q.Name + ".retry"
+}
+
+// Declara o exchange e a fila de retentativa. A fila não define routing key de
+// dead letter: a mensagem volta com a routing key usada na publicação, que é
+// o nome da fila de origem.
+func (q retryQueue) Declare(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(q.retryName(), "fanout", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("Erro ao declarar exchange %s: %v", q.retryName(), err)
+	}
+
+	_, err = ch.QueueDeclare(q.retryName(), true, false, false, false, amqp.Table{
+		"x-message-ttl":          int32(q.Delay / time.Millisecond),
+		"x-dead-letter-exchange": "",
+	})
+	if err != nil {
+		return fmt.Errorf("Erro ao declarar fila %s: %v", q.retryName(), err)
+	}
+
+	err = ch.QueueBind(q.retryName(), "", q.retryName(), false, nil)
+	if err != nil {
+		return fmt.Errorf("Erro ao associar fila %s: %v", q.retryName(), err)
+	}
+	return nil
+}
+
+// Erro que não passa com novas tentativas (JSON inválido, versão desconhecida...)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Finaliza a entrega conforme o resultado do processamento: ack no sucesso,
+// fila de retentativa em erros temporários e dead_letters quando desistir
+func (q retryQueue) Settle(ch *amqp.Channel, d amqp.Delivery, err error) {
+	if err == nil {
+		d.Ack(false)
+		return
+	}
+
+	attempts := deathCount(d.Headers, q.retryName()) + 1
+	if shouldDeadLetter(attempts, q.MaxAttempts, err) {
+		log.Printf("Mensagem da fila %s enviada para dead letters após %d tentativas: %v", q.Queue, attempts, err)
+		q.deadLetter(ch, d, err, attempts)
+		return
+	}
+
+	log.Printf("Erro ao processar mensagem da fila %s (tentativa %d/%d), nova tentativa em %s: %v", q.Queue, attempts, q.MaxAttempts, q.Delay, err)
+	pubErr := ch.Publish(q.retryName(), q.Queue, false, false, retryPublishing(d))
+	if pubErr != nil {
+		// Sem a fila de retentativa, devolve para a fila de origem
+		log.Printf("Erro ao publicar na fila %s: %v", q.retryName(), pubErr)
+		d.Nack(false, true)
+		return
+	}
+	d.Ack(false)
+}
+
+func (q retryQueue) deadLetter(ch *amqp.Channel, d amqp.Delivery, cause error, attempts int) {
+	headers, err := json.Marshal(d.Headers)
+	if err != nil {
+		headers = []byte("{}")
+	}
+
+	err = db.CreateDeadLetter(&db.DeadLetter{
+		Queue:    q.Queue,
+		Body:     string(d.Body),
+		Headers:  string(headers),
+		Reason:   cause.Error(),
+		Attempts: attempts,
+	})
+	if err != nil {
+		// A mensagem não pode se perder: volta para a fila de retentativa
+		log.Printf("Erro ao salvar dead letter da fila %s: %v", q.Queue, err)
+		pubErr := ch.Publish(q.retryName(), q.Queue, false, false, retryPublishing(d))
+		if pubErr != nil {
+			d.Nack(false, true)
+			return
+		}
+	}
+	d.Ack(false)
+}
+
+// Erros permanentes vão direto para dead_letters; os demais, quando a
+// tentativa atual chega ao limite
+func shouldDeadLetter(attempts int, maxAttempts int, err error) bool {
+	var permanentErr permanentError
+	return errors.As(err, &permanentErr) || attempts >= maxAttempts
+}
+
+// Mensagem publicada na fila de retentativa. Os headers originais seguem
+// junto para o x-death continuar a contagem.
+func retryPublishing(d amqp.Delivery) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:      d.Headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         d.Body,
+	}
+}
+
+// Quantas vezes a mensagem já expirou na fila de retentativa, segundo o x-death
+func deathCount(headers amqp.Table, queue string) int {
+	deaths, ok := headers["x-death"].([]interface{})
+	if !ok {
+		return 0
+	}
+	for _, death := range deaths {
+		entry, ok := death.(amqp.Table)
+		if !ok || entry["queue"] != queue {
+			continue
+		}
+		switch count := entry["count"].(type) {
+		case int64:
+			return int(count)
+		case int32:
+			return int(count)
+		case int:
+			return count
+		}
+	}
+	return 0
+}
+
+// Headers guardados em JSON prontos para publicar de novo. O x-death é
+// descartado para a mensagem reenviada recomeçar a contagem, e os números
+// voltam a ser inteiros (o JSON os transforma em float64).
+func redriveHeaders(stored string) amqp.Table {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(stored), &raw); err != nil {
+		return nil
+	}
+
+	headers := amqp.Table{}
+	for key, value := range raw {
+		if strings.HasPrefix(key, "x-death") || strings.HasPrefix(key, "x-first-death") || strings.HasPrefix(key, "x-last-death") {
+			continue
+		}
+		if number, ok := value.(float64); ok && number == float64(int32(number)) {
+			headers[key] = int32(number)
+			continue
+		}
+		if _, ok := value.(string); ok {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+// Publica as mensagens reenviadas pela API com publisher confirms. A
+// publicação é mandatory: se a fila de origem não existir mais o broker
+// devolve a mensagem em vez de descartá-la.
+type deadLetterPublisher struct {
+	confirmTimeout time.Duration
+
+	mu       sync.Mutex
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	tag      uint64 // delivery tag da última mensagem publicada no canal
+}
+
+func newDeadLetterPublisher(ch *amqp.Channel, confirmTimeout time.Duration) (*deadLetterPublisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %v", err)
+	}
+	return &deadLetterPublisher{
+		confirmTimeout: confirmTimeout,
+		ch:             ch,
+		confirms:       ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:        ch.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+// Devolve a mensagem para a fila de origem pelo exchange padrão e espera o
+// broker confirmar que ela chegou na fila
+func (p *deadLetterPublisher) Redrive(deadLetter *db.DeadLetter) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.ch.Publish("", deadLetter.Queue, true, false, amqp.Publishing{
+		Headers:      redriveHeaders(deadLetter.Headers),
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         []byte(deadLetter.Body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish dead letter %s: %v", deadLetter.ID, err)
+	}
+	p.tag++
+
+	timeout := time.After(p.confirmTimeout)
+	for {
+		select {
+		case confirm, ok := <-p.confirms:
+			if !ok {
+				return fmt.Errorf("RabbitMQ channel closed before confirming dead letter %s", deadLetter.ID)
+			}
+			returned := p.pendingReturn()
+			// Confirmação atrasada de um reenvio que já tinha estourado o tempo
+			if confirm.DeliveryTag < p.tag {
+				continue
+			}
+			if returned != nil {
+				return fmt.Errorf("RabbitMQ returned dead letter %s: %s (queue %s)", deadLetter.ID, returned.ReplyText, deadLetter.Queue)
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("RabbitMQ rejected dead letter %s (delivery tag %d)", deadLetter.ID, confirm.DeliveryTag)
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for RabbitMQ confirmation of dead letter %s after %s", deadLetter.ID, p.confirmTimeout)
+		}
+	}
+}
+
+// O broker manda o basic.return antes do ack da mesma mensagem, então quando
+// a confirmação chega o retorno dela já está no canal
+func (p *deadLetterPublisher) pendingReturn() *amqp.Return {
+	select {
+	case ret := <-p.returns:
+		return &ret
+	default:
+		return nil
+	}
+}
+
+const defaultDeadLetterLimit = 50
+
+// GET /dead-letters?queue=&status=pending|all&limit=
+func deadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultDeadLetterLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "pending" && status != "all" {
+		http.Error(w, "Invalid status value, must be pending or all", http.StatusBadRequest)
+		return
+	}
+
+	deadLetters, err := db.ListDeadLetters(r.URL.Query().Get("queue"), status == "all", limit)
+	if err != nil {
+		log.Printf("Erro ao listar dead letters: %v", err)
+		http.Error(w, "Erro ao listar dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deadLetters)
+}
+
+// GET /dead-letters/{id}
+func deadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deadLetter, ok := loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, deadLetter)
+}
+
+// POST /dead-letters/{id}/redrive
+func redriveDeadLetterHandler(w http.ResponseWriter, r *http.Request, publisher *deadLetterPublisher) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deadLetter, ok := loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	if deadLetter.RedrivenAt != nil && r.URL.Query().Get("force") != "true" {
+		http.Error(w, "Dead letter already redriven, use force=true to send it again", http.StatusConflict)
+		return
+	}
+
+	if err := publisher.Redrive(deadLetter); err != nil {
+		log.Printf("Erro ao reenviar dead letter %s: %v", deadLetter.ID, err)
+		http.Error(w, "Erro ao reenviar a mensagem", http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now()
+	if err := db.MarkDeadLetterRedriven(deadLetter.ID, now); err != nil {
+		log.Printf("%v", err)
+	}
+	deadLetter.RedrivenAt = &now
+	log.Printf("Dead letter %s reenviada para a fila %s", deadLetter.ID, deadLetter.Queue)
+
+	writeJSON(w, http.StatusOK, deadLetter)
+}
+
+func loadDeadLetter(w http.ResponseWriter, r *http.Request) (*db.DeadLetter, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid dead letter id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	deadLetter, err := db.GetDeadLetter(id)
+	if err != nil {
+		log.Printf("Erro ao buscar dead letter %s: %v", id, err)
+		http.Error(w, "Erro ao buscar dead letter", http.StatusInternalServerError)
+		return nil, false
+	}
+	if deadLetter == nil {
+		http.Error(w, "Dead letter not found", http.StatusNotFound)
+		return nil, false
+	}
+	return deadLetter, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Printf("Erro ao escrever resposta JSON: %v", err)
+	}
+}
+
+func getEnvInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %d", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
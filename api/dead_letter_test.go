@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestDeathCount(t *testing.T) {
+	headers := amqp.Table{
+		"x-death": []interface{}{
+			amqp.Table{"queue": "google_places.retry", "count": int64(3)},
+			amqp.Table{"queue": "company.retry", "count": int32(1)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		queue   string
+		count   int
+	}{
+		{name: "int64 count", headers: headers, queue: "google_places.retry", count: 3},
+		{name: "int32 count", headers: headers, queue: "company.retry", count: 1},
+		{name: "other queue", headers: headers, queue: "cnpj.retry", count: 0},
+		{name: "no x-death", headers: amqp.Table{}, queue: "google_places.retry", count: 0},
+		{name: "nil headers", headers: nil, queue: "google_places.retry", count: 0},
+	}
+	for _, tt := range tests {
+		if count := deathCount(tt.headers, tt.queue); count != tt.count {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.count, count)
+		}
+	}
+}
+
+func TestShouldDeadLetter(t *testing.T) {
+	temporary := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     bool
+	}{
+		{name: "first attempt", attempts: 1, err: temporary, want: false},
+		{name: "before the limit", attempts: 4, err: temporary, want: false},
+		{name: "at the limit", attempts: 5, err: temporary, want: true},
+		{name: "permanent error", attempts: 1, err: permanent(errors.New("invalid JSON")), want: true},
+		{name: "wrapped permanent error", attempts: 1, err: fmt.Errorf("lead: %w", permanent(errors.New("unknown version"))), want: true},
+	}
+	for _, tt := range tests {
+		if got := shouldDeadLetter(tt.attempts, 5, tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRetryPublishingKeepsHeaders(t *testing.T) {
+	delivery := amqp.Delivery{
+		Headers:     amqp.Table{"x-death": []interface{}{amqp.Table{"queue": "google_places.retry", "count": int64(1)}}},
+		ContentType: "application/json",
+		Body:        []byte(`{"place_id":"place_a"}`),
+	}
+
+	publishing := retryPublishing(delivery)
+	if !reflect.DeepEqual(publishing.Headers, delivery.Headers) {
+		t.Errorf("expected headers %v, got %v", delivery.Headers, publishing.Headers)
+	}
+	if publishing.DeliveryMode != amqp.Persistent || publishing.ContentType != "application/json" || string(publishing.Body) != string(delivery.Body) {
+		t.Errorf("unexpected publishing %+v", publishing)
+	}
+}
+
+func TestRedriveHeaders(t *testing.T) {
+	stored := `{
+		"x-death": [{"queue": "google_places.retry", "count": 5}],
+		"x-first-death-queue": "google_places.retry",
+		"x-last-death-reason": "expired",
+		"version": 2,
+		"ratio": 0.5,
+		"source": "lead-search",
+		"nested": {"a": 1}
+	}`
+
+	expected := amqp.Table{"version": int32(2), "source": "lead-search"}
+	if headers := redriveHeaders(stored); !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected %v, got %v", expected, headers)
+	}
+	if headers := redriveHeaders("not json"); headers != nil {
+		t.Errorf("expected nil headers for invalid JSON, got %v", headers)
+	}
+}
@@ -26,5 +26,7 @@ require (
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
-
+	shared v0.0.0
 )
+
+replace shared => ../shared
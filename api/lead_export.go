@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"api/db"
+)
+
+// A cada quantas linhas a resposta é enviada ao cliente
+const exportFlushEvery = 500
+
+// Escreve os leads num formato de arquivo
+type leadExportWriter interface {
+	WriteHeader(columns []db.LeadColumn, lang string) error
+	WriteLead(columns []db.LeadColumn, lead *db.Lead) error
+	Flush() error
+	Close() error
+}
+
+var leadExportFormats = map[string]struct {
+	contentType string
+	extension   string
+	open        func(w http.ResponseWriter) (leadExportWriter, error)
+}{
+	"csv": {"text/csv; charset=utf-8", "csv", func(w http.ResponseWriter) (leadExportWriter, error) {
+		return &csvLeadWriter{csv: csv.NewWriter(w)}, nil
+	}},
+	"jsonl": {"application/x-ndjson", "jsonl", func(w http.ResponseWriter) (leadExportWriter, error) {
+		buffered := bufio.NewWriter(w)
+		return &jsonlLeadWriter{buffer: buffered, encoder: json.NewEncoder(buffered)}, nil
+	}},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", func(w http.ResponseWriter) (leadExportWriter, error) {
+		xlsx, err := newXLSXWriter(w, "Leads")
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxLeadWriter{xlsx: xlsx}, nil
+	}},
+}
+
+// GET /leads/export?format=csv|xlsx|jsonl&columns=&lang=pt|en&sort=
+// e os mesmos filtros de GET /leads
+func exportLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := leadExportFormats[formatName]
+	if !ok {
+		http.Error(w, "Invalid format, must be csv, xlsx or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseLeadFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sort, err := db.ParseLeadSort(query.Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	columns, err := db.ParseLeadColumns(query.Get("columns"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lang := query.Get("lang")
+
+	// A resposta só começa com o primeiro lead (ou no fim, se não houver
+	// nenhum), para que um erro na consulta ainda possa virar um 500
+	var writer leadExportWriter
+	start := func() error {
+		filename := fmt.Sprintf("leads-%s.%s", time.Now().Format("20060102-150405"), format.extension)
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+
+		writer, err = format.open(w)
+		if err != nil {
+			return err
+		}
+		return writer.WriteHeader(columns, lang)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	err = db.StreamLeads(filter, sort, func(lead *db.Lead) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteLead(columns, lead); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Erro ao exportar leads: %v", err)
+		if writer == nil {
+			http.Error(w, "Erro ao exportar leads", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if writer == nil {
+		if err := start(); err != nil {
+			log.Printf("Erro ao exportar leads: %v", err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Erro ao finalizar exportação de leads: %v", err)
+		return
+	}
+	log.Printf("%d leads exportados em %s", count, formatName)
+}
+
+func leadRecord(columns []db.LeadColumn, lead *db.Lead) []string {
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.Format(lead)
+	}
+	return record
+}
+
+func leadHeaders(columns []db.LeadColumn, lang string) []string {
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header(lang)
+	}
+	return headers
+}
+
+type csvLeadWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvLeadWriter) WriteHeader(columns []db.LeadColumn, lang string) error {
+	return w.csv.Write(leadHeaders(columns, lang))
+}
+
+func (w *csvLeadWriter) WriteLead(columns []db.LeadColumn, lead *db.Lead) error {
+	record := leadRecord(columns, lead)
+	for i := range record {
+		record[i] = csvSafeText(record[i])
+	}
+	return w.csv.Write(record)
+}
+
+// Planilhas abrem como fórmula a célula que começa com =, +, - ou @. Com o
+// apóstrofo na frente o Excel mostra o texto como veio; a importação o remove.
+func csvSafeText(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+const csvFormulaPrefixes = "=+-@"
+
+func (w *csvLeadWriter) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvLeadWriter) Close() error {
+	return w.Flush()
+}
+
+// Uma linha JSON por lead, com os nomes das colunas como chaves
+type jsonlLeadWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonlLeadWriter) WriteHeader(columns []db.LeadColumn, lang string) error {
+	return nil
+}
+
+func (w *jsonlLeadWriter) WriteLead(columns []db.LeadColumn, lead *db.Lead) error {
+	row := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		row[column.Name] = column.Value(lead)
+	}
+	return w.encoder.Encode(row)
+}
+
+func (w *jsonlLeadWriter) Flush() error {
+	return w.buffer.Flush()
+}
+
+func (w *jsonlLeadWriter) Close() error {
+	return w.Flush()
+}
+
+type xlsxLeadWriter struct {
+	xlsx *xlsxWriter
+}
+
+func (w *xlsxLeadWriter) WriteHeader(columns []db.LeadColumn, lang string) error {
+	return w.xlsx.Write(leadHeaders(columns, lang))
+}
+
+func (w *xlsxLeadWriter) WriteLead(columns []db.LeadColumn, lead *db.Lead) error {
+	return w.xlsx.Write(leadRecord(columns, lead))
+}
+
+func (w *xlsxLeadWriter) Flush() error {
+	return w.xlsx.Flush()
+}
+
+func (w *xlsxLeadWriter) Close() error {
+	return w.xlsx.Close()
+}
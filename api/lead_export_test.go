@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+
+	"api/db"
+)
+
+// Sem colunas e sem idioma o CSV exportado tem o cabeçalho do dump original
+func TestCSVExportHeaderMatchesDump(t *testing.T) {
+	file, err := os.Open("../data-021024.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	firstLine, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := db.ParseLeadColumns("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	writer := &csvLeadWriter{csv: csv.NewWriter(&buffer)}
+	if err := writer.WriteHeader(columns, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// O dump põe todos os nomes entre aspas; o encoding/csv só quando precisa
+	expected := strings.ReplaceAll(strings.TrimSpace(firstLine), `"`, "")
+	if got := strings.TrimSpace(buffer.String()); got != expected {
+		t.Errorf("expected header\n%s\ngot\n%s", expected, got)
+	}
+}
+
+// Células que o Excel abriria como fórmula saem com apóstrofo na frente
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	columns, err := db.ParseLeadColumns("business_name,phone,email,city")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lead := &db.Lead{
+		BusinessName: `=HYPERLINK("http://example.com","Padaria")`,
+		Phone:        "+551128921688",
+		Email:        "@contato",
+		City:         "São Paulo",
+	}
+
+	var buffer bytes.Buffer
+	writer := &csvLeadWriter{csv: csv.NewWriter(&buffer)}
+	if err := writer.WriteLead(columns, lead); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := csv.NewReader(&buffer).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`'=HYPERLINK("http://example.com","Padaria")`, "'+551128921688", "'@contato", "São Paulo"}
+	if strings.Join(record, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, record)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"api/db"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultHistoryLimit = 100
+	defaultLeadsLimit   = 50
+	maxLeadsLimit       = 500
+)
+
+// /leads: GET lista os leads, POST associa um CNPJ (usado pelo scrapper)
+func leadsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listLeadsHandler(w, r)
+	case http.MethodPost:
+		leadHandler(w, r)
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /leads?city=&state=&category=&quality=&business_status=&search_term=
+// &has_phone=&has_whatsapp=&has_email=&min_rating=&max_rating=
+// &created_from=&created_to=&sort=-created_at&limit=&cursor=
+func listLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := parseLeadFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sort, err := db.ParseLeadSort(query.Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultLeadsLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxLeadsLimit {
+			http.Error(w, fmt.Sprintf("Invalid limit value, must be between 1 and %d", maxLeadsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var cursor *db.LeadCursor
+	if value := query.Get("cursor"); value != "" {
+		cursor, err = db.DecodeLeadCursor(value, sort)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	leads, next, err := db.QueryLeads(filter, sort, cursor, limit)
+	if err != nil {
+		log.Printf("%v", err)
+		http.Error(w, "Erro ao buscar leads", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"leads":       leads,
+		"next_cursor": nil,
+	}
+	if next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GET /leads/{id}
+func getLeadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	leadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead id", http.StatusBadRequest)
+		return
+	}
+
+	lead, err := db.GetLeadWithSteps(leadID)
+	if err != nil {
+		log.Printf("Erro ao buscar lead %s: %v", leadID, err)
+		http.Error(w, "Erro ao buscar lead", http.StatusInternalServerError)
+		return
+	}
+	if lead == nil {
+		http.Error(w, "Lead not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lead)
+}
+
+func parseLeadFilter(query url.Values) (db.LeadFilter, error) {
+	filter := db.LeadFilter{
+		City:           query.Get("city"),
+		State:          query.Get("state"),
+		Category:       query.Get("category"),
+		Quality:        query.Get("quality"),
+		BusinessStatus: query.Get("business_status"),
+		SearchTerm:     query.Get("search_term"),
+	}
+
+	var err error
+	if filter.HasPhone, err = parseOptionalBool(query, "has_phone"); err != nil {
+		return filter, err
+	}
+	if filter.HasWhatsapp, err = parseOptionalBool(query, "has_whatsapp"); err != nil {
+		return filter, err
+	}
+	if filter.HasEmail, err = parseOptionalBool(query, "has_email"); err != nil {
+		return filter, err
+	}
+	if filter.MinRating, err = parseOptionalFloat(query, "min_rating"); err != nil {
+		return filter, err
+	}
+	if filter.MaxRating, err = parseOptionalFloat(query, "max_rating"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseOptionalDate(query, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseOptionalDate(query, "created_to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseOptionalBool(query url.Values, name string) (*bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s value, must be true or false", name)
+	}
+	return &parsed, nil
+}
+
+func parseOptionalFloat(query url.Values, name string) (*float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s value", name)
+	}
+	return &parsed, nil
+}
+
+// Aceita YYYY-MM-DD ou RFC 3339. Em um limite final só com a data, o dia
+// inteiro entra no intervalo.
+func parseOptionalDate(query url.Values, name string, end bool) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s value, must be YYYY-MM-DD or RFC 3339", name)
+	}
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
+}
+
+// GET /leads/{id}/history?field=&limit=
+func leadHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	leadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead id", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	lead, err := db.GetLeadByID(leadID)
+	if err != nil {
+		log.Printf("Erro ao buscar lead %s: %v", leadID, err)
+		http.Error(w, "Erro ao buscar lead", http.StatusInternalServerError)
+		return
+	}
+	if lead == nil {
+		http.Error(w, "Lead not found", http.StatusNotFound)
+		return
+	}
+
+	history, err := db.GetLeadFieldHistory(leadID, r.URL.Query().Get("field"), limit)
+	if err != nil {
+		log.Printf("%v", err)
+		http.Error(w, "Erro ao buscar histórico do lead", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"api/db"
+	"shared/phone"
+)
+
+const (
+	maxImportSize       = 100 << 20
+	defaultImportSource = "import"
+)
+
+var importEmailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// Resultado de uma linha da importação
+type importRowReport struct {
+	Row           int      `json:"row"`
+	Status        string   `json:"status"` // created, updated, unchanged, duplicate_in_file, invalid, failed
+	LeadID        string   `json:"lead_id,omitempty"`
+	MatchedBy     string   `json:"matched_by,omitempty"`
+	ChangedFields []string `json:"changed_fields,omitempty"`
+	Errors        []string `json:"errors,omitempty"`
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+type importReport struct {
+	DryRun         bool              `json:"dry_run"`
+	Source         string            `json:"source"`
+	Total          int               `json:"total"`
+	Counts         map[string]int    `json:"counts"`
+	Mapping        map[string]string `json:"mapping"`
+	IgnoredColumns []string          `json:"ignored_columns"`
+	Rows           []importRowReport `json:"rows"`
+}
+
+// Linha lida do arquivo: valores por nome de coluna de origem
+type importRecord map[string]string
+
+// POST /leads/import?format=csv|jsonl&dry_run=true&source=&mapping={"Nome":"business_name"}
+//
+// O arquivo vai no corpo ou no campo "file" de um multipart; os parâmetros
+// também podem vir como campos do formulário. Colunas sem mapeamento são
+// associadas pelo nome da coluna (como no data-021024.csv) ou pelo cabeçalho
+// da exportação em pt/en.
+func importLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	body, params, err := importInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	dryRun := false
+	if value := params("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid dry_run value, must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	source := params("source")
+	if source == "" {
+		source = defaultImportSource
+	}
+
+	mapping := map[string]string{}
+	if value := params("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			http.Error(w, "Invalid mapping, must be a JSON object from file column to lead column", http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := params("format")
+	if format == "" {
+		format = "csv"
+		if strings.Contains(r.Header.Get("Content-Type"), "ndjson") {
+			format = "jsonl"
+		}
+	}
+
+	var records func(fn func(importRecord) error) ([]string, error)
+	switch format {
+	case "csv":
+		records = func(fn func(importRecord) error) ([]string, error) { return readCSVRecords(body, fn) }
+	case "jsonl":
+		records = func(fn func(importRecord) error) ([]string, error) { return readJSONLRecords(body, fn) }
+	default:
+		http.Error(w, "Invalid format, must be csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	importer := &leadImporter{
+		dryRun:  dryRun,
+		source:  source,
+		mapping: mapping,
+		seen:    make(map[string]int),
+		report: importReport{
+			DryRun:         dryRun,
+			Source:         source,
+			Counts:         make(map[string]int),
+			Mapping:        make(map[string]string),
+			IgnoredColumns: []string{},
+			Rows:           []importRowReport{},
+		},
+	}
+
+	headers, err := records(importer.importRecord)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if importer.report.Total == 0 {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Linhas anteriores já foram gravadas; o relatório mostra até onde foi
+		log.Printf("Importação interrompida na linha %d: %v", importer.report.Total+1, err)
+		importer.report.Rows = append(importer.report.Rows, importRowReport{
+			Row:    importer.report.Total + 2,
+			Status: "failed",
+			Errors: []string{err.Error()},
+		})
+		importer.report.Counts["failed"]++
+	}
+	importer.resolveColumns(headers)
+
+	log.Printf("Importação de leads (%s, dry_run=%v): %d linhas, %v", source, dryRun, importer.report.Total, importer.report.Counts)
+	writeJSON(w, http.StatusOK, importer.report)
+}
+
+// Corpo do arquivo e os parâmetros, vindos da query ou do formulário multipart
+func importInput(r *http.Request) (io.ReadCloser, func(string) string, error) {
+	query := r.URL.Query()
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, query.Get, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid multipart body: %v", err)
+	}
+
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("Missing file field")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid multipart body: %v", err)
+		}
+		// O arquivo precisa ser a última parte, pois é lido direto da requisição
+		if part.FormName() == "file" {
+			params := func(name string) string {
+				if value, ok := fields[name]; ok {
+					return value
+				}
+				return query.Get(name)
+			}
+			return part, params, nil
+		}
+		value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid multipart body: %v", err)
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
+
+func readCSVRecords(body io.Reader, fn func(importRecord) error) ([]string, error) {
+	reader := csv.NewReader(bufio.NewReader(body))
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Empty file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV header: %v", err)
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\uFEFF")
+	}
+
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return headers, nil
+		}
+		if err != nil {
+			return headers, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		record := make(importRecord, len(headers))
+		for i, header := range headers {
+			if i < len(values) {
+				record[header] = csvUnescapeText(values[i])
+			}
+		}
+		if err := fn(record); err != nil {
+			return headers, err
+		}
+	}
+}
+
+// Desfaz o apóstrofo que a exportação põe antes de =, +, - e @ (ver csvSafeText)
+func csvUnescapeText(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func readJSONLRecords(body io.Reader, fn func(importRecord) error) ([]string, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 10<<20)
+
+	var headers []string
+	known := make(map[string]bool)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return headers, fmt.Errorf("Invalid JSON line: %v", err)
+		}
+
+		record := make(importRecord, len(object))
+		for key, value := range object {
+			if !known[key] {
+				known[key] = true
+				headers = append(headers, key)
+			}
+			record[key] = jsonText(value)
+		}
+		if err := fn(record); err != nil {
+			return headers, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return headers, fmt.Errorf("Invalid JSONL: %v", err)
+	}
+	return headers, nil
+}
+
+func jsonText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+type leadImporter struct {
+	dryRun  bool
+	source  string
+	mapping map[string]string // coluna do arquivo -> coluna do lead
+	columns map[string]db.LeadColumn
+	seen    map[string]int // chaves de deduplicação já vistas no arquivo -> linha
+	report  importReport
+}
+
+// Associa cada coluna do arquivo a uma coluna do lead
+func (imp *leadImporter) resolveColumn(header string) (db.LeadColumn, bool) {
+	if imp.columns == nil {
+		imp.columns = make(map[string]db.LeadColumn)
+	}
+	if column, ok := imp.columns[header]; ok {
+		return column, column.Name != ""
+	}
+
+	name := strings.TrimSpace(header)
+	if target, ok := imp.mapping[header]; ok {
+		name = target
+	}
+
+	var resolved db.LeadColumn
+	for _, column := range db.LeadColumns {
+		if column.Name == name || column.Field == name || strings.EqualFold(column.Labels["pt"], name) || strings.EqualFold(column.Labels["en"], name) {
+			resolved = column
+			break
+		}
+	}
+	imp.columns[header] = resolved
+	return resolved, resolved.Name != ""
+}
+
+func (imp *leadImporter) resolveColumns(headers []string) {
+	for _, header := range headers {
+		if column, ok := imp.resolveColumn(header); ok {
+			imp.report.Mapping[header] = column.Name
+		} else {
+			imp.report.IgnoredColumns = append(imp.report.IgnoredColumns, header)
+		}
+	}
+}
+
+func (imp *leadImporter) importRecord(record importRecord) error {
+	imp.report.Total++
+	row := importRowReport{Row: imp.report.Total + 1} // a linha 1 é o cabeçalho
+
+	lead, errs := imp.parseLead(record)
+	if len(errs) > 0 {
+		row.Status = "invalid"
+		row.Errors = errs
+		imp.addRow(row)
+		return nil
+	}
+	row.Warnings = parseImportedPhones(lead, imp.source)
+
+	// Duplicados dentro do próprio arquivo
+	for _, key := range importKeys(lead) {
+		if first, ok := imp.seen[key]; ok {
+			row.Status = "duplicate_in_file"
+			row.Errors = []string{fmt.Sprintf("same %s as row %d", strings.SplitN(key, ":", 2)[0], first)}
+			imp.addRow(row)
+			return nil
+		}
+	}
+	for _, key := range importKeys(lead) {
+		imp.seen[key] = row.Row
+	}
+
+	existing, matchedBy, err := db.FindDuplicateLead(lead)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		row.Status = "created"
+		if !imp.dryRun {
+			if err := db.CreateImportedLead(lead); err != nil {
+				row.Status = "failed"
+				row.Errors = []string{err.Error()}
+				imp.addRow(row)
+				return nil
+			}
+			row.LeadID = lead.ID.String()
+		}
+		imp.addRow(row)
+		return nil
+	}
+
+	row.MatchedBy = matchedBy
+	row.LeadID = existing.ID.String()
+	row.ChangedFields = db.MergeLead(existing, lead, db.ImportMergeRules)
+	if len(row.ChangedFields) == 0 {
+		row.Status = "unchanged"
+		if !imp.dryRun {
+			if err := db.SaveLeadPhones(existing.ID, lead.Phones); err != nil {
+				row.Status = "failed"
+				row.Errors = []string{err.Error()}
+			}
+		}
+		imp.addRow(row)
+		return nil
+	}
+
+	row.Status = "updated"
+	if !imp.dryRun {
+		existing.ChangeSource = imp.source
+		err := db.SaveLeadChanges(existing, row.ChangedFields, "Lead Atualizado por Importação")
+		if err == nil {
+			err = db.SaveLeadPhones(existing.ID, lead.Phones)
+		}
+		if err != nil {
+			row.Status = "failed"
+			row.Errors = []string{err.Error()}
+		}
+	}
+	imp.addRow(row)
+	return nil
+}
+
+func (imp *leadImporter) addRow(row importRowReport) {
+	imp.report.Counts[row.Status]++
+	imp.report.Rows = append(imp.report.Rows, row)
+}
+
+// Devolve o lead com os erros de validação (que impedem a importação da linha)
+func (imp *leadImporter) parseLead(record importRecord) (*db.Lead, []string) {
+	lead := &db.Lead{}
+	var errs []string
+	for header, value := range record {
+		column, ok := imp.resolveColumn(header)
+		if !ok {
+			continue
+		}
+		if err := column.Parse(lead, value); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errs
+	}
+
+	if lead.Source == "" {
+		lead.Source = imp.source
+	}
+	lead.ChangeSource = imp.source
+	return lead, validateImportedLead(lead)
+}
+
+func validateImportedLead(lead *db.Lead) []string {
+	var errs []string
+	if strings.TrimSpace(lead.BusinessName) == "" && strings.TrimSpace(lead.RegisteredName) == "" {
+		errs = append(errs, "business_name or registered_name is required")
+	}
+	if lead.GoogleId == "" && lead.CompanyRegistrationID == "" && lead.Phone == "" {
+		errs = append(errs, "google_id, company_registration_id or phone is required for deduplication")
+	}
+	if cnpj := db.NormalizeCNPJ(lead.CompanyRegistrationID); lead.CompanyRegistrationID != "" && len(cnpj) != 14 {
+		errs = append(errs, fmt.Sprintf("company_registration_id: invalid CNPJ %q", lead.CompanyRegistrationID))
+	}
+	if lead.Email != "" && !importEmailPattern.MatchString(lead.Email) {
+		errs = append(errs, fmt.Sprintf("email: invalid email %q", lead.Email))
+	}
+	if lead.Rating < 0 || lead.Rating > 5 {
+		errs = append(errs, fmt.Sprintf("rating: must be between 0 and 5, got %v", lead.Rating))
+	}
+	return errs
+}
+
+// Chaves de deduplicação do lead dentro do arquivo
+func importKeys(lead *db.Lead) []string {
+	var keys []string
+	if lead.GoogleId != "" {
+		keys = append(keys, "google_id:"+lead.GoogleId)
+	}
+	if cnpj := db.NormalizeCNPJ(lead.CompanyRegistrationID); cnpj != "" {
+		keys = append(keys, "cnpj:"+cnpj)
+	}
+	for _, leadPhone := range lead.Phones {
+		keys = append(keys, "phone:"+leadPhone.Number)
+	}
+	return keys
+}
+
+// Telefones das colunas phone e whatsapp (podem ter vários números separados
+// por vírgula) viram lead.Phones; Phone e Whatsapp ficam com o principal em
+// E.164. Números inválidos não impedem a importação: voltam como avisos, e
+// sem nenhum número válido o texto original fica em Phone.
+func parseImportedPhones(lead *db.Lead, source string) []string {
+	var warnings []string
+	var phones []db.LeadPhone
+	index := make(map[string]int)
+	for _, column := range []struct {
+		name     string
+		value    string
+		whatsapp bool
+	}{
+		{"phone", lead.Phone, false},
+		{"whatsapp", lead.Whatsapp, true},
+	} {
+		if strings.TrimSpace(column.value) == "" {
+			continue
+		}
+		numbers, parseErrs := phone.ParseAll(column.value)
+		for _, err := range parseErrs {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", column.name, err))
+		}
+		for _, number := range numbers {
+			if i, ok := index[number.E164()]; ok {
+				phones[i].Whatsapp = phones[i].Whatsapp || column.whatsapp
+				continue
+			}
+			leadPhone := db.NewLeadPhone(number, source)
+			leadPhone.Whatsapp = column.whatsapp
+			index[number.E164()] = len(phones)
+			phones = append(phones, leadPhone)
+		}
+	}
+	if len(phones) == 0 {
+		return warnings
+	}
+
+	// O principal é o primeiro da coluna phone; sem ela, o primeiro WhatsApp
+	lead.Phone, lead.Whatsapp = "", ""
+	for _, leadPhone := range phones {
+		if lead.Phone == "" {
+			lead.Phone = leadPhone.Number
+		}
+		if lead.Whatsapp == "" && leadPhone.Whatsapp {
+			lead.Whatsapp = leadPhone.Number
+		}
+	}
+	lead.Phones = phones
+	return warnings
+}
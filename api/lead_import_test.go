@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"api/db"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveColumn(t *testing.T) {
+	imp := &leadImporter{mapping: map[string]string{"Nome": "business_name", "Fone": "Phone"}}
+
+	tests := []struct {
+		header string
+		column string
+	}{
+		{header: "Nome", column: "business_name"},
+		{header: "Fone", column: "phone"},
+		{header: "z_ip_code", column: "z_ip_code"},
+		{header: "GoogleId", column: "google_id"},
+		{header: "razão social", column: "registered_name"},
+		{header: "Company registration ID", column: "company_registration_id"},
+		{header: " city ", column: "city"},
+		{header: "observações", column: ""},
+	}
+	for _, tt := range tests {
+		column, ok := imp.resolveColumn(tt.header)
+		if ok != (tt.column != "") || column.Name != tt.column {
+			t.Errorf("%q: expected column %q, got %q (ok %v)", tt.header, tt.column, column.Name, ok)
+		}
+	}
+
+	// O resultado fica guardado por cabeçalho
+	if column, ok := imp.columns["Nome"]; !ok || column.Name != "business_name" {
+		t.Errorf("expected Nome to be cached, got %+v", imp.columns)
+	}
+}
+
+func TestParseImportedPhones(t *testing.T) {
+	lead := &db.Lead{
+		Phone:    "+55 11 2892-1688, (11) 98765-4321",
+		Whatsapp: "11 98765-4321",
+	}
+
+	warnings := parseImportedPhones(lead, db.SourceManual)
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+	if lead.Phone != "+551128921688" || lead.Whatsapp != "+5511987654321" {
+		t.Errorf("unexpected primary phones %q and %q", lead.Phone, lead.Whatsapp)
+	}
+	if len(lead.Phones) != 2 {
+		t.Fatalf("expected 2 phones, got %+v", lead.Phones)
+	}
+	if lead.Phones[0].Whatsapp || !lead.Phones[1].Whatsapp {
+		t.Errorf("expected only the mobile number to be marked as WhatsApp, got %+v", lead.Phones)
+	}
+	for _, leadPhone := range lead.Phones {
+		if leadPhone.Source != db.SourceManual {
+			t.Errorf("expected source %s, got %s", db.SourceManual, leadPhone.Source)
+		}
+	}
+}
+
+func TestParseImportedPhonesKeepsInvalidText(t *testing.T) {
+	lead := &db.Lead{Phone: "+55 35998755"}
+
+	warnings := parseImportedPhones(lead, db.SourceManual)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "phone: ") {
+		t.Errorf("expected one phone warning, got %v", warnings)
+	}
+	if lead.Phone != "+55 35998755" || len(lead.Phones) != 0 {
+		t.Errorf("expected the original text to be kept, got %q and %+v", lead.Phone, lead.Phones)
+	}
+}
+
+func TestImportKeys(t *testing.T) {
+	lead := &db.Lead{
+		GoogleId:              "ChIJt_PsxzX2zpQRYLNGTDlE8-Q",
+		CompanyRegistrationID: "12.345.678/0001-90",
+		Phones:                []db.LeadPhone{{Number: "+551128921688"}, {Number: "+5511987654321"}},
+	}
+
+	expected := []string{
+		"google_id:ChIJt_PsxzX2zpQRYLNGTDlE8-Q",
+		"cnpj:12345678000190",
+		"phone:+551128921688",
+		"phone:+5511987654321",
+	}
+	if keys := importKeys(lead); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys %v, got %v", expected, keys)
+	}
+	if keys := importKeys(&db.Lead{BusinessName: "Padaria"}); len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+}
+
+// Um lead exportado em CSV volta igual pela importação
+func TestImportCSVRoundTrip(t *testing.T) {
+	lead := &db.Lead{
+		ID:                    uuid.New(),
+		BusinessName:          "Padaria Central",
+		Address:               "Rua Augusta, 100 - Consolação",
+		City:                  "São Paulo",
+		Phone:                 "+551128921688",
+		Email:                 "contato@padariacentral.com.br",
+		CompanyRegistrationID: "12.345.678/0001-90",
+		Categories:            "bakery, food",
+		Rating:                4.6,
+		UserRatingsTotal:      128,
+		PermanentlyClosed:     true,
+		Description:           "Pães, \"doces\" e café\nAberta aos domingos",
+		GoogleId:              "ChIJt_PsxzX2zpQRYLNGTDlE8-Q",
+		CreatedAt:             time.Date(2024, 9, 27, 17, 5, 2, 205164000, time.UTC),
+		Source:                db.SourceGooglePlaces,
+	}
+
+	var buffer bytes.Buffer
+	writer := &csvLeadWriter{csv: csv.NewWriter(&buffer)}
+	if err := writer.WriteHeader(db.DefaultLeadColumns, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteLead(db.DefaultLeadColumns, lead); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	imp := &leadImporter{source: db.SourceManual}
+	var imported []*db.Lead
+	_, err := readCSVRecords(&buffer, func(record importRecord) error {
+		parsed, errs := imp.parseLead(record)
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		imported = append(imported, parsed)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("expected 1 lead, got %d", len(imported))
+	}
+
+	for _, column := range db.DefaultLeadColumns {
+		if column.Format(imported[0]) != column.Format(lead) {
+			t.Errorf("%s: expected %q, got %q", column.Name, column.Format(lead), column.Format(imported[0]))
+		}
+	}
+}
+
+func TestReadRecords(t *testing.T) {
+	// Planilhas salvas pelo Excel começam com BOM
+	csvHeaders, err := readCSVRecords(strings.NewReader("\uFEFFbusiness_name,rating\nPadaria,4.5\n"), func(record importRecord) error {
+		if record["business_name"] != "Padaria" || record["rating"] != "4.5" {
+			t.Errorf("unexpected CSV record %v", record)
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(csvHeaders, []string{"business_name", "rating"}) {
+		t.Errorf("unexpected CSV headers %v (err %v)", csvHeaders, err)
+	}
+
+	jsonlHeaders, err := readJSONLRecords(strings.NewReader(`{"business_name": "Padaria", "rating": 4.5, "permanently_closed": false, "phone": null}`+"\n\n"), func(record importRecord) error {
+		if record["rating"] != "4.5" || record["permanently_closed"] != "false" || record["phone"] != "" {
+			t.Errorf("unexpected JSONL record %v", record)
+		}
+		return nil
+	})
+	if err != nil || len(jsonlHeaders) != 4 {
+		t.Errorf("unexpected JSONL headers %v (err %v)", jsonlHeaders, err)
+	}
+}
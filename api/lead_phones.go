@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+
+	"api/db"
+	"shared/phone"
+)
+
+// Interpreta os números de raw e, se checkWhatsApp, verifica cada um na API
+// de WhatsApp. Números inválidos ficam só no log.
+func parseLeadPhones(raw string, source string, checkWhatsApp bool) []db.LeadPhone {
+	numbers, errs := phone.ParseAll(raw)
+	for _, err := range errs {
+		log.Printf("Telefone ignorado: %v", err)
+	}
+
+	phones := make([]db.LeadPhone, 0, len(numbers))
+	for _, number := range numbers {
+		leadPhone := db.NewLeadPhone(number, source)
+		if checkWhatsApp {
+			hasWhatsapp, err := hasWhatsApp(number.E164())
+			if err != nil {
+				log.Printf("Erro ao verificar WhatsApp de %s: %v", number.E164(), err)
+			}
+			leadPhone.Whatsapp = hasWhatsapp
+		}
+		phones = append(phones, leadPhone)
+	}
+	return phones
+}
+
+// Preenche Phone e Whatsapp do lead com o primeiro número de cada tipo, sem
+// trocar os que já existem, e devolve os campos alterados
+func setPrimaryPhones(lead *db.Lead, phones []db.LeadPhone) []string {
+	var changed []string
+	for _, leadPhone := range phones {
+		if lead.Phone == "" {
+			lead.Phone = leadPhone.Number
+			changed = append(changed, "Phone")
+		}
+		if lead.Whatsapp == "" && leadPhone.Whatsapp {
+			lead.Whatsapp = leadPhone.Number
+			changed = append(changed, "Whatsapp")
+		}
+	}
+	return changed
+}
+
+// Grava os telefones de um lead existente e, se mudaram, o Phone e o Whatsapp
+// principais
+func saveLeadPhones(lead *db.Lead, phones []db.LeadPhone) error {
+	if err := db.SaveLeadPhones(lead.ID, phones); err != nil {
+		return err
+	}
+	if changed := setPrimaryPhones(lead, phones); len(changed) > 0 {
+		return db.SaveLeadChanges(lead, changed, "Telefones Atualizados")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"api/db"
+)
+
+func TestSetPrimaryPhonesKeepsExistingNumbers(t *testing.T) {
+	lead := db.Lead{Phone: "+551134567890"}
+	phones := []db.LeadPhone{
+		{Number: "+551140041234"},
+		{Number: "+5511987654321", Whatsapp: true},
+	}
+
+	changed := setPrimaryPhones(&lead, phones)
+	if len(changed) != 1 || changed[0] != "Whatsapp" {
+		t.Fatalf("expected only Whatsapp to change, got %v", changed)
+	}
+	if lead.Phone != "+551134567890" || lead.Whatsapp != "+5511987654321" {
+		t.Errorf("unexpected primary phones %q and %q", lead.Phone, lead.Whatsapp)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"api/db"
 	"bytes"
 	"database/sql"
+	"shared/leadmessage"
 
-	"regexp"
 	"strconv"
 
 	"context"
@@ -56,10 +56,6 @@ func main() {
 	}
 	defer conn.Close()
 
-	leadsChannel, err := setupChannel(conn)
-	if err != nil {
-		log.Fatalf("Erro ao configurar canal para leads: %v", err)
-	}
 	companiesChannel, err := setupChannel(conn)
 	if err != nil {
 		log.Fatalf("Erro ao configurar canal para companies: %v", err)
@@ -68,7 +64,6 @@ func main() {
 	if err != nil {
 		log.Fatalf("Erro ao configurar canal para Google Places: %v", err)
 	}
-	defer leadsChannel.Close()
 	defer companiesChannel.Close()
 	defer googlePlacesChannel.Close()
 
@@ -90,16 +85,32 @@ func main() {
 		log.Fatalf("Erro ao migrar o banco de dados: %v", err)
 	}
 
-	log.Println("Starting to consume leads from RabbitMQ...")
-	go consumeLeadsFromRabbitMQ(leadsChannel)
-
 	log.Println("Starting to consume companies from RabbitMQ...")
 	go consumeCompaniesFromRabbitMQ(companiesChannel)
 
 	log.Println("Starting to consume Google Places leads from RabbitMQ...")
-	go consumeGooglePlacesLeads(googlePlacesChannel)
+	go consumeGooglePlacesLeads(googlePlacesChannel, loadGooglePlacesQueueConfig())
 
-	http.HandleFunc("/leads", leadHandler)
+	deadLetterChannel, err := setupChannel(conn)
+	if err != nil {
+		log.Fatalf("Erro ao configurar canal para dead letters: %v", err)
+	}
+	defer deadLetterChannel.Close()
+	deadLetters, err := newDeadLetterPublisher(deadLetterChannel, getEnvDuration("REDRIVE_CONFIRM_TIMEOUT", 5*time.Second))
+	if err != nil {
+		log.Fatalf("Erro ao configurar canal para dead letters: %v", err)
+	}
+
+	http.HandleFunc("/leads", leadsHandler)
+	http.HandleFunc("/leads/{id}", getLeadHandler)
+	http.HandleFunc("/leads/export", exportLeadsHandler)
+	http.HandleFunc("/leads/import", importLeadsHandler)
+	http.HandleFunc("/leads/{id}/history", leadHistoryHandler)
+	http.HandleFunc("/dead-letters", deadLettersHandler)
+	http.HandleFunc("/dead-letters/{id}", deadLetterHandler)
+	http.HandleFunc("/dead-letters/{id}/redrive", func(w http.ResponseWriter, r *http.Request) {
+		redriveDeadLetterHandler(w, r, deadLetters)
+	})
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -109,38 +120,64 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
-func consumeGooglePlacesLeads(ch *amqp.Channel) {
-	queueName := "leads_exchange"
+// Configuração da fila dos leads do Google Places. A fila é durável e tem
+// nome fixo para não perder os leads publicados com a api fora do ar.
+type googlePlacesQueueConfig struct {
+	Queue     string
+	Prefetch  int // mensagens sem ack entregues de uma vez (QoS)
+	Consumers int // goroutines processando a fila
+}
+
+func loadGooglePlacesQueueConfig() googlePlacesQueueConfig {
+	config := googlePlacesQueueConfig{
+		Queue:     os.Getenv("GOOGLE_PLACES_QUEUE"),
+		Prefetch:  getEnvInt("GOOGLE_PLACES_PREFETCH", 10),
+		Consumers: getEnvInt("GOOGLE_PLACES_CONSUMERS", 4),
+	}
+	if config.Queue == "" {
+		config.Queue = "api_google_places_leads"
+	}
+	if config.Consumers < 1 {
+		config.Consumers = 1
+	}
+	if config.Prefetch < config.Consumers {
+		config.Prefetch = config.Consumers
+	}
+	return config
+}
+
+func consumeGooglePlacesLeads(ch *amqp.Channel, config googlePlacesQueueConfig) {
+	exchangeName := "leads_exchange"
 
 	err := ch.ExchangeDeclare(
-		queueName, // Exchange de onde as mensagens vêm
-		"fanout",  // Tipo de exchange
-		true,      // Durável
-		false,     // Auto-delete
-		false,     // Interno
-		false,     // No-wait
-		nil,       // Argumentos adicionais
+		exchangeName, // Exchange de onde as mensagens vêm
+		"fanout",     // Tipo de exchange
+		true,         // Durável
+		false,        // Auto-delete
+		false,        // Interno
+		false,        // No-wait
+		nil,          // Argumentos adicionais
 	)
 	if err != nil {
 		log.Fatalf("Erro ao declarar exchange: %v", err)
 	}
 
 	q, err := ch.QueueDeclare(
-		"",
-		false, // Não persistente
+		config.Queue,
+		true,  // Durável
 		false, // Auto-delete
-		true,  // Exclusivo para esta conexão
+		false, // Exclusivo
 		false,
 		nil,
 	)
 	if err != nil {
-		log.Fatalf("Erro ao declarar fila: %v", err)
+		log.Fatalf("Erro ao declarar fila %s: %v", config.Queue, err)
 	}
 
 	err = ch.QueueBind(
 		q.Name,
 		"",
-		queueName,
+		exchangeName,
 		false,
 		nil,
 	)
@@ -148,6 +185,16 @@ func consumeGooglePlacesLeads(ch *amqp.Channel) {
 		log.Fatalf("Erro ao associar fila ao exchange: %v", err)
 	}
 
+	err = ch.Qos(config.Prefetch, 0, false)
+	if err != nil {
+		log.Fatalf("Erro ao configurar QoS: %v", err)
+	}
+
+	retry := newRetryQueue(q.Name, q.Name)
+	if err := retry.Declare(ch); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	msgs, err := ch.Consume(
 		q.Name,
 		"",
@@ -161,30 +208,31 @@ func consumeGooglePlacesLeads(ch *amqp.Channel) {
 		log.Fatalf("Erro ao registrar consumidor: %v", err)
 	}
 
-	log.Println("Consumindo leads do Google Places...")
-
-	go func() {
-		for d := range msgs {
-			log.Printf("Lead recebido do Google Places: %s", string(d.Body))
+	log.Printf("Consumindo leads do Google Places da fila %s (%d mensagens, %d consumidores)...", q.Name, config.Prefetch, config.Consumers)
 
-			var leadData map[string]interface{}
-			if err := json.Unmarshal(d.Body, &leadData); err != nil {
-				log.Printf("Erro ao decodificar JSON: %v", err)
-				d.Nack(false, true) // Reenviar a mensagem para a fila
-				continue
+	for i := 0; i < config.Consumers; i++ {
+		go func() {
+			for d := range msgs {
+				log.Printf("Lead recebido do Google Places: %s", string(d.Body))
+				err := processGooglePlacesLead(d)
+				retry.Settle(ch, d, err)
+				if err == nil {
+					log.Println("Lead do Google Places salvo com sucesso!")
+				}
 			}
+		}()
+	}
+}
 
-			err := saveLeadToDatabase(leadData)
-			if err != nil {
-				log.Printf("Erro ao salvar lead no banco de dados: %v", err)
-				d.Nack(false, false) // Descartar a mensagem
-				continue
-			}
+func processGooglePlacesLead(d amqp.Delivery) error {
+	// Versão desconhecida ou campos inválidos: reprocessar não mudaria o resultado
+	leadData, err := leadmessage.Decode(d.Headers[leadmessage.VersionHeader], d.Body)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid lead message: %v", err))
+	}
 
-			d.Ack(false) // Confirmação de processamento bem-sucedido
-			log.Println("Lead do Google Places salvo com sucesso!")
-		}
-	}()
+	// Reentregas e novas buscas do mesmo lugar só atualizam o lead salvo
+	return saveLeadToDatabase(leadData)
 }
 
 func hasWhatsApp(phone string) (bool, error) {
@@ -459,79 +507,82 @@ func consumeCompaniesFromRabbitMQ(ch *amqp.Channel) {
 		log.Fatalf("Failed to register a consumer: %v", err)
 	}
 
+	retry := newRetryQueue(queueName, q.Name)
+	if err := retry.Declare(ch); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	go func() {
 		for d := range msgs {
-
 			log.Printf("Mensagem recebida do scrapper via companies_exchange: %s", string(d.Body))
+			retry.Settle(ch, d, processCompanyMessage(d))
+		}
+	}()
 
-			var combinedData map[string]interface{}
-
-			err := json.Unmarshal(d.Body, &combinedData)
-			if err != nil {
-				log.Printf("Erro ao decodificar JSON: %v", err)
-				d.Nack(false, true)
-				continue
-			}
+	log.Println("Consumindo empresas do RabbitMQ...")
+	select {} // Block para manter o consumer ativo
+}
 
-			// Buscar o google_id dos dados
-			googleId, ok := combinedData["google_id"].(string)
-			if !ok {
-				log.Printf("google_id não encontrado na mensagem: %v", combinedData)
-				d.Nack(false, true)
-				continue
-			}
+// Atualiza o lead com os dados de CNPJ enviados pelo scrapper. O lead pode
+// ainda não estar no Redis quando a mensagem chega; nesse caso a mensagem
+// volta pela fila de retentativa.
+func processCompanyMessage(d amqp.Delivery) error {
+	var combinedData map[string]interface{}
 
-			// Buscar o lead_id usando o google_id do Redis
-			leadIdStr, err := redisClient.Get(ctx, fmt.Sprintf("google_lead:%s", googleId)).Result()
-			if err != nil {
-				log.Printf("Erro ao buscar lead_id no Redis para google_id %s: %v", googleId, err)
-				d.Nack(false, true)
-				continue
-			}
+	err := json.Unmarshal(d.Body, &combinedData)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid JSON: %v", err))
+	}
 
-			leadId, err := uuid.Parse(leadIdStr)
-			if err != nil {
-				log.Printf("Erro ao fazer parse do lead_id %s: %v", leadIdStr, err)
-				d.Nack(false, false)
-				continue
-			}
+	// Buscar o google_id dos dados
+	googleId, ok := combinedData["google_id"].(string)
+	if !ok {
+		return permanent(fmt.Errorf("google_id not found in message"))
+	}
 
-			// Processar dados do CNPJ
-			cnpjDataList, ok := combinedData["cnpj_data"].([]interface{})
-			if !ok || len(cnpjDataList) == 0 {
-				log.Printf("cnpj_data não encontrado ou vazio: %v", combinedData)
-				d.Nack(false, false)
-				continue
-			}
+	// Buscar o lead_id usando o google_id do Redis
+	leadIdStr, err := redisClient.Get(ctx, fmt.Sprintf("google_lead:%s", googleId)).Result()
+	if err == redis.Nil {
+		return fmt.Errorf("lead_id not found in Redis for google_id %s", googleId)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lead_id from Redis for google_id %s: %v", googleId, err)
+	}
 
-			// Usar o primeiro CNPJ válido encontrado
-			for _, cnpjData := range cnpjDataList {
-				cnpjMap, ok := cnpjData.(map[string]interface{})
-				if !ok {
-					continue
-				}
+	leadId, err := uuid.Parse(leadIdStr)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid lead_id %s: %v", leadIdStr, err))
+	}
 
-				// Atualizar o lead com os dados do CNPJ
-				err = updateLeadWithCNPJData(leadId, cnpjMap)
-				if err != nil {
-					log.Printf("Erro ao atualizar lead %s com dados do CNPJ: %v", leadId, err)
-					continue
-				}
+	// Processar dados do CNPJ
+	cnpjDataList, ok := combinedData["cnpj_data"].([]interface{})
+	if !ok || len(cnpjDataList) == 0 {
+		return permanent(fmt.Errorf("cnpj_data not found or empty"))
+	}
 
-				log.Printf("Lead %s atualizado com sucesso com dados do CNPJ", leadId)
-				break // Usar apenas o primeiro CNPJ válido
-			}
+	// Usar o primeiro CNPJ válido encontrado
+	for _, cnpjData := range cnpjDataList {
+		cnpjMap, ok := cnpjData.(map[string]interface{})
+		if !ok {
+			continue
+		}
 
-			d.Ack(false) // Confirmar processamento
-			log.Printf("Dados do CNPJ processados com sucesso para google_id: %s, lead_id: %s", googleId, leadId)
+		// Atualizar o lead com os dados do CNPJ
+		err = updateLeadWithCNPJData(leadId, cnpjMap)
+		if err != nil {
+			log.Printf("Erro ao atualizar lead %s com dados do CNPJ: %v", leadId, err)
+			continue
 		}
-	}()
 
-	log.Println("Consumindo empresas do RabbitMQ...")
-	select {} // Block para manter o consumer ativo
+		log.Printf("Lead %s atualizado com sucesso com dados do CNPJ", leadId)
+		break // Usar apenas o primeiro CNPJ válido
+	}
+
+	log.Printf("Dados do CNPJ processados com sucesso para google_id: %s, lead_id: %s", googleId, leadId)
+	return nil
 }
 
-func saveLeadToDatabase(data map[string]interface{}) error {
+func saveLeadToDatabase(data leadmessage.LeadMessage) error {
 	log.Println("Iniciando processamento do lead...")
 	lead := db.Lead{}
 
@@ -540,51 +591,39 @@ func saveLeadToDatabase(data map[string]interface{}) error {
 		log.Printf("Gerado novo UUID para lead: %s", lead.ID)
 	}
 
-	if v, ok := data["Name"].(string); ok {
-		lead.BusinessName = v
-		log.Printf("Nome do negócio: %s", lead.BusinessName)
-	}
+	lead.BusinessName = data.Name
+	log.Printf("Nome do negócio: %s", lead.BusinessName)
 
-	if v, ok := data["FormattedAddress"].(string); ok {
-		lead.Address = v
-		log.Printf("Endereço formatado: %s", lead.Address)
-		if lead.Address == "" {
-			log.Printf("Aviso: Endereço vazio para o PlaceID %s", data["PlaceID"])
-		}
+	lead.Address = data.FormattedAddress
+	log.Printf("Endereço formatado: %s", lead.Address)
+	if lead.Address == "" {
+		log.Printf("Aviso: Endereço vazio para o PlaceID %s", data.PlaceID)
 	}
 
-	if v, ok := data["City"].(string); ok {
-		lead.City = v
-		log.Printf("Cidade: %s", lead.City)
-	}
-	if v, ok := data["State"].(string); ok {
-		lead.State = v
-		log.Printf("Estado: %s", lead.State)
-	}
-	if v, ok := data["ZIPCode"].(string); ok {
-		lead.ZIPCode = v
-		log.Printf("CEP: %s", lead.ZIPCode)
-	}
-
-	if v, ok := data["Country"].(string); ok {
-		lead.Country = v
-		log.Printf("País: %s", lead.Country)
+	// Sem a cidade do endereço, fica a cidade da busca
+	lead.City = data.City
+	if lead.City == "" {
+		lead.City = data.SearchCity
 	}
+	log.Printf("Cidade: %s", lead.City)
+	lead.State = data.State
+	lead.ZIPCode = data.ZIPCode
+	lead.Country = data.Country
+	log.Printf("Estado: %s, CEP: %s, País: %s", lead.State, lead.ZIPCode, lead.Country)
 
-	if v, ok := data["InternationalPhoneNumber"].(string); ok {
+	var phones []db.LeadPhone
+	if v := data.InternationalPhoneNumber; v != "" {
 		log.Printf("Verificando WhatsApp para o telefone: %s", v)
-		hasWhatsapp, err := hasWhatsApp(v)
-		if err != nil {
-			log.Printf("Erro ao verificar WhatsApp: %v", err)
-		} else if hasWhatsapp {
-			lead.Whatsapp = v
-			log.Println("WhatsApp confirmado e salvo.", lead.Whatsapp)
+		phones = parseLeadPhones(v, db.SourceGooglePlaces, true)
+		setPrimaryPhones(&lead, phones)
+		if lead.Phone == "" {
+			// Número fora do padrão brasileiro: guarda como veio
+			lead.Phone = v
 		}
-		lead.Phone = v
 	}
 	log.Printf("Lead preparado para salvar - Nome: %s, Phone: %s, WhatsApp: %s", lead.BusinessName, lead.Phone, lead.Whatsapp)
 
-	if v, ok := data["Email"].(string); ok {
+	if v := data.Email; v != "" {
 		log.Printf("Validando email: %s", v)
 		isValidEmail, err := validateEmail(v)
 		if err != nil {
@@ -597,7 +636,7 @@ func saveLeadToDatabase(data map[string]interface{}) error {
 		}
 	}
 
-	if v, ok := data["Website"].(string); ok {
+	if v := data.Website; v != "" {
 		lead.Website = v
 		log.Printf("Website: %s", lead.Website)
 		if strings.HasPrefix(lead.Website, "https://www.instagram.com") {
@@ -612,70 +651,46 @@ func saveLeadToDatabase(data map[string]interface{}) error {
 		}
 	}
 
-	if v, ok := data["Description"].(string); ok {
-		if lead.Description != "" {
-			lead.Description = fmt.Sprintf("%s\n%s", lead.Description, v)
-		} else {
-			lead.Description = v
-		}
+	if data.Description != "" {
+		lead.Description = data.Description
 		log.Println("Descrição atualizada.")
 	}
 
-	if v, ok := data["Rating"].(float64); ok {
-		lead.Rating = v
-		log.Printf("Avaliação: %.2f", lead.Rating)
-	}
-	if v, ok := data["UserRatingsTotal"].(float64); ok {
-		lead.UserRatingsTotal = int(v)
-		log.Printf("Total de avaliações: %d", lead.UserRatingsTotal)
-	}
-	if v, ok := data["PriceLevel"].(float64); ok {
-		lead.PriceLevel = int(v)
-		log.Printf("Nível de preço: %d", lead.PriceLevel)
-	}
-	if v, ok := data["BusinessStatus"].(string); ok {
-		lead.BusinessStatus = v
-		log.Printf("Status do negócio: %s", lead.BusinessStatus)
-	}
-	if v, ok := data["Vicinity"].(string); ok {
-		lead.Vicinity = v
-	}
-	if v, ok := data["PermanentlyClosed"].(bool); ok {
-		lead.PermanentlyClosed = v
-		log.Printf("Fechado permanentemente: %v", lead.PermanentlyClosed)
-	}
-	if v, ok := data["Types"].([]interface{}); ok {
-		var types []string
-		for _, t := range v {
-			if typeStr, ok := t.(string); ok {
-				types = append(types, typeStr)
-			}
-		}
-		lead.Categories = strings.Join(types, ", ")
-		log.Printf("Categorias: %s", lead.Categories)
-	}
+	lead.Rating = data.Rating
+	lead.UserRatingsTotal = data.UserRatingsTotal
+	lead.PriceLevel = data.PriceLevel
+	log.Printf("Avaliação: %.2f, total de avaliações: %d, nível de preço: %d", lead.Rating, lead.UserRatingsTotal, lead.PriceLevel)
 
-	if category, ok := data["Category"].(string); ok {
-		if city, ok := data["City"].(string); ok {
-			radius := data["Radius"]
-			lead.SearchTerm = fmt.Sprintf("%s, %s, %v", category, city, radius)
-			log.Printf("Termo de busca salvo: %s", lead.SearchTerm)
-		}
-	}
+	lead.BusinessStatus = data.BusinessStatus
+	lead.Vicinity = data.Vicinity
+	lead.PermanentlyClosed = data.PermanentlyClosed
+	log.Printf("Status do negócio: %s, fechado permanentemente: %v", lead.BusinessStatus, lead.PermanentlyClosed)
+
+	lead.Categories = strings.Join(data.Types, ", ")
+	lead.OpeningHours = strings.Join(data.OpeningHours, "; ")
 
-	if v, ok := data["PlaceID"].(string); ok {
-		lead.GoogleId = v
-		log.Printf("Google ID: %s", lead.GoogleId)
+	if data.Category != "" && data.SearchCity != "" {
+		lead.SearchTerm = fmt.Sprintf("%s, %s, %v", data.Category, data.SearchCity, data.Radius)
+		log.Printf("Termo de busca salvo: %s", lead.SearchTerm)
 	}
 
-	lead.Source = "GooglePlaces"
+	lead.GoogleId = data.PlaceID
+	log.Printf("Google ID: %s", lead.GoogleId)
+
+	lead.Source = db.SourceGooglePlaces
+	lead.ChangeSource = db.SourceGooglePlaces
+	lead.Phones = phones
 	log.Println("Tentando salvar lead no banco de dados...")
-	err := db.CreateLead(&lead)
+	created, changed, err := db.UpsertLead(&lead, db.GooglePlacesMergeRules)
 	if err != nil {
 		log.Printf("Erro ao salvar lead no banco de dados: %v", err)
 		return fmt.Errorf("Failed to save lead to database: %v", err)
 	}
-	log.Printf("Lead salvo no banco de dados: %v", lead)
+	if created {
+		log.Printf("Lead salvo no banco de dados: %v", lead)
+	} else {
+		log.Printf("Lead %s já existia, campos alterados: %v", lead.GoogleId, changed)
+	}
 
 	log.Println("Tentando salvar lead no Redis...")
 	err = SaveLeadToRedis(lead.GoogleId, lead.ID)
@@ -686,18 +701,28 @@ func saveLeadToDatabase(data map[string]interface{}) error {
 
 	log.Printf("Lead salvo no Redis: Google ID %s -> Lead ID %s", lead.GoogleId, lead.ID)
 
+	// Só leads novos vão para o scrapper. A pendência é gravada junto com o
+	// lead, então a retentativa de uma mensagem que falhou depois do upsert
+	// ainda envia o lead
+	pending, err := db.ScrapperPending(lead.ID)
+	if err != nil {
+		return err
+	}
+	if !pending {
+		return nil
+	}
+
 	// Enviar confirmação para o scrapper processar o lead
 	log.Printf("Enviando lead para o scrapper: Google ID %s", lead.GoogleId)
 	err = sendConfirmationToScrapper(lead.GoogleId)
 	if err != nil {
 		log.Printf("Erro ao enviar lead para o scrapper: %v", err)
-		// Não retornamos erro aqui para não falhar o salvamento do lead
-		// O scrapper pode processar posteriormente via retry ou outra lógica
-	} else {
-		log.Printf("Lead enviado com sucesso para o scrapper")
+		// A mensagem volta pela fila de retentativa e o envio é refeito
+		return fmt.Errorf("Failed to send lead to scrapper: %v", err)
 	}
+	log.Printf("Lead enviado com sucesso para o scrapper")
 
-	return nil
+	return db.MarkSentToScrapper(lead.ID)
 }
 
 func saveCompanyData(data map[string]interface{}) (uuid.UUID, error) {
@@ -739,6 +764,7 @@ func saveCompanyData(data map[string]interface{}) (uuid.UUID, error) {
 			existingLead.City = data["company_city"].(string)
 
 			log.Printf("Iniciando atualização do Lead com Google ID: %s", existingLead.GoogleId)
+			existingLead.ChangeSource = db.SourceScrapper
 			err = db.UpdateLead(existingLead)
 			log.Printf("Atualização do Lead com Google ID: %s concluída", existingLead.GoogleId)
 			if err != nil {
@@ -770,6 +796,24 @@ func updateLeadWithCNPJData(leadID uuid.UUID, cnpjData map[string]interface{}) e
 		return fmt.Errorf("Lead não encontrado com ID: %s", leadID)
 	}
 
+	lead.ChangeSource = db.SourceCNPJBiz
+	phones := applyCNPJData(lead, cnpjData)
+	if err := saveLeadPhones(lead, phones); err != nil {
+		return err
+	}
+
+	// Salvar o lead atualizado
+	err = db.UpdateLead(lead)
+	if err != nil {
+		return fmt.Errorf("Erro ao atualizar lead no banco de dados: %v", err)
+	}
+
+	log.Printf("Lead %s atualizado com sucesso com dados do CNPJ", leadID)
+	return nil
+}
+
+// Copia para o lead os dados do cnpj.biz e devolve os telefones encontrados
+func applyCNPJData(lead *db.Lead, cnpjData map[string]interface{}) []db.LeadPhone {
 	// Atualizar CNPJ
 	if cnpj, ok := cnpjData["cnpj"].(string); ok && cnpj != "" {
 		// Formatar CNPJ se necessário
@@ -800,9 +844,10 @@ func updateLeadWithCNPJData(leadID uuid.UUID, cnpjData map[string]interface{}) e
 	}
 
 	// Atualizar Telefones
-	if telefone1, ok := cnpjData["telefone1"].(string); ok && telefone1 != "" {
-		if lead.Phone == "" {
-			lead.Phone = telefone1
+	var phones []db.LeadPhone
+	for _, key := range []string{"telefone1", "telefone2"} {
+		if telefone, ok := cnpjData[key].(string); ok && telefone != "" {
+			phones = append(phones, parseLeadPhones(telefone, db.SourceCNPJBiz, false)...)
 		}
 	}
 
@@ -929,14 +974,7 @@ func updateLeadWithCNPJData(leadID uuid.UUID, cnpjData map[string]interface{}) e
 		}
 	}
 
-	// Salvar o lead atualizado
-	err = db.UpdateLead(lead)
-	if err != nil {
-		return fmt.Errorf("Erro ao atualizar lead no banco de dados: %v", err)
-	}
-
-	log.Printf("Lead %s atualizado com sucesso com dados do CNPJ", leadID)
-	return nil
+	return phones
 }
 
 func updateLeadWithCNPJDetailsByID(leadID uuid.UUID, cnpjDetails map[string]interface{}) error {
@@ -949,6 +987,8 @@ func updateLeadWithCNPJDetailsByID(leadID uuid.UUID, cnpjDetails map[string]inte
 		return fmt.Errorf("Lead não encontrado com ID: %s", leadID)
 	}
 
+	lead.ChangeSource = db.SourceCNPJBiz
+
 	var newDescriptions []string
 
 	if v, ok := cnpjDetails["razao_social"].(string); ok {
@@ -1039,58 +1079,14 @@ func updateLeadWithCNPJDetailsByID(leadID uuid.UUID, cnpjDetails map[string]inte
 			lead.Owner = strings.Join(ownerDetails, ", ")
 		}
 
-		normalizePhone := func(phone string) string {
-			re := regexp.MustCompile(`[\s\-\(\)]`)
-			return re.ReplaceAllString(phone, "")
-		}
-
-		formatPhone := func(phone string) string {
-			phone = normalizePhone(phone)
-			if len(phone) == 11 {
-				return fmt.Sprintf("+55 %s %s-%s", phone[:2], phone[2:6], phone[6:])
+		var phones []db.LeadPhone
+		for _, key := range []string{"telefone1", "telefone2"} {
+			if telefone, ok := cnpjDetails[key].(string); ok && telefone != "" {
+				phones = append(phones, parseLeadPhones(telefone, db.SourceCNPJBiz, true)...)
 			}
-			return phone
 		}
-
-		var newPhones []string
-
-		if telefone1, ok := cnpjDetails["telefone1"].(string); ok && telefone1 != "" {
-			hasWhatsapp, _ := hasWhatsApp(telefone1)
-			formattedPhone := formatPhone(telefone1)
-			newPhones = append(newPhones, formattedPhone)
-			if hasWhatsapp {
-				lead.Whatsapp += formattedPhone + ", "
-			}
-		}
-
-		if telefone2, ok := cnpjDetails["telefone2"].(string); ok && telefone2 != "" {
-			hasWhatsapp, _ := hasWhatsApp(telefone2)
-			formattedPhone := formatPhone(telefone2)
-			newPhones = append(newPhones, formattedPhone)
-			if hasWhatsapp {
-				lead.Whatsapp += formattedPhone + ", "
-			}
-		}
-
-		if lead.Phone != "" {
-			existingPhones := strings.Split(lead.Phone, ", ")
-			normalizedExistingPhones := make(map[string]bool)
-
-			for _, existingPhone := range existingPhones {
-				normalizedExistingPhones[normalizePhone(existingPhone)] = true
-			}
-
-			for _, newPhone := range newPhones {
-				if !normalizedExistingPhones[normalizePhone(newPhone)] {
-					existingPhones = append(existingPhones, newPhone)
-				}
-			}
-
-			lead.Phone = strings.Join(existingPhones, ", ")
-
-		} else {
-
-			lead.Phone = strings.Join(newPhones, ", ")
+		if err := saveLeadPhones(lead, phones); err != nil {
+			return err
 		}
 
 		if v, ok := cnpjDetails["porte"].(string); ok {
@@ -1191,6 +1187,7 @@ func leadHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Falha ao enviar confirmação para o scrapper antes", http.StatusInternalServerError)
 		return
 	}
+	existingLead.ChangeSource = db.SourceScrapper
 	err = db.UpdateLead(existingLead)
 	if err != nil {
 		log.Printf("Erro ao atualizar o lead: %v", err)
@@ -1223,61 +1220,6 @@ func leadHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Lead e LeadStep salvos com sucesso!")
 }
 
-func consumeLeadsFromRabbitMQ(ch *amqp.Channel) {
-	q, err := ch.QueueDeclare(
-		"leads_queue",
-		true,  // Durable
-		false, // Delete when unused
-		false, // Exclusive
-		false, // No-wait
-		nil,   // Arguments
-	)
-	if err != nil {
-		log.Printf("Mensagens pendentes na fila %s: %d", q.Name, q.Messages)
-		log.Fatalf("Failed to declare queue: %v", err)
-	}
-
-	msgs, err := ch.Consume(
-		q.Name,
-		"",
-		false, // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,   // Arguments
-	)
-	if err != nil {
-		log.Fatalf("Failed to register a consumer: %v", err)
-	}
-	log.Println("Consumidor registrado com sucesso")
-
-	go func() {
-		for d := range msgs {
-			log.Printf("Mensagem recebida: %s", d.Body)
-
-			var leadData map[string]interface{}
-			if err := json.Unmarshal(d.Body, &leadData); err != nil {
-				log.Printf("Erro ao decodificar JSON: %v", err)
-				d.Nack(false, true)
-				continue
-			}
-			log.Println("Mensagem decodificada com sucesso")
-
-			if err := saveLeadToDatabase(leadData); err != nil {
-				log.Printf("Erro ao processar lead: %v", err)
-				d.Nack(false, false)
-				continue
-			}
-			log.Println("Mensagem processada com sucesso!")
-
-			d.Ack(false)
-		}
-	}()
-
-	log.Println("Consumindo leads do RabbitMQ...")
-	select {} // Block para manter o consumer ativo
-}
-
 func closeRabbitMQ() {
 	if rabbitChannel != nil {
 		rabbitChannel.Close()
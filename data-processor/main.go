@@ -6,23 +6,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 var db *sql.DB
 
-type Lead struct {
-	ID               int
-	Name             string
-	Address          string
-	Phone            string
-	Website          string
-	Quality          string
-	FieldsFilled     int
-}
-
-
 func connectDB() {
 	var err error
 	connStr := "user=postgres password=postgres dbname=leadsdb host=db sslmode=disable"
@@ -38,67 +28,34 @@ func connectDB() {
 	fmt.Println("Successfully connected to the database.")
 }
 
-
-// func evaluateQuality() {
-// 	rows, err := db.Query("SELECT id, nome, endereco, telefone, site FROM leads WHERE qualidade IS NULL")
-// 	if err != nil {
-// 		log.Fatalf("Error fetching leads: %v", err)
-// 	}
-// 	defer rows.Close()
-
-// 	for rows.Next() {
-// 		var lead Lead
-// 		err := rows.Scan(&lead.ID, &lead.Name, &lead.Address, &lead.Phone, &lead.Website)
-// 		if err != nil {
-// 			log.Fatalf("Error reading leads: %v", err)
-// 		}
-
-		
-// 		lead.FieldsFilled = 0
-// 		if lead.Name !=  {
-// 			lead.FieldsFilled++
-// 		}
-// 		if lead.Address !=  {
-// 			lead.FieldsFilled++
-// 		}
-// 		if lead.Phone !=  {
-// 			lead.FieldsFilled++
-// 		}
-// 		if lead.Website !=  {
-// 			lead.FieldsFilled++
-// 		}
-
-		
-// 		if lead.FieldsFilled == 4 {
-// 			lead.Quality = "good"
-// 		} else {
-// 			lead.Quality = "bad"
-// 		}
-
-		
-// 		_, err = db.Exec("UPDATE leads SET qualidade = $1, campos_preenchidos = $2 WHERE id = $3",
-// 			lead.Quality, lead.FieldsFilled, lead.ID)
-// 		if err != nil {
-// 			log.Printf("Error updating lead %d: %v", lead.ID, err)
-// 		}
-// 	}
-
-// 	if err = rows.Err(); err != nil {
-// 		log.Fatalf("Error iterating over leads: %v", err)
-// 	}
-
-// 	fmt.Println("Lead quality updated successfully.")
-// }
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
 
 func main() {
-	
 	connectDB()
 	defer db.Close()
 
-	
-	// evaluateQuality()
+	config, err := loadScoringConfig()
+	if err != nil {
+		log.Fatalf("Error loading scoring config: %v", err)
+	}
+
+	scoring := &scorer{db: db, config: config}
+	go scoring.Run(
+		getEnvDuration("SCORE_NEW_INTERVAL", 30*time.Second),
+		getEnvDuration("RESCORE_INTERVAL", 24*time.Hour),
+	)
 
-	
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
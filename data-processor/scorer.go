@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const scoreBatchSize = 500
+
+// Colunas lidas para pontuar um lead
+const scoreColumns = `
+	id::text, COALESCE(business_name, ''), COALESCE(address, ''), COALESCE(phone, ''),
+	COALESCE(whatsapp, ''), COALESCE(email, ''), COALESCE(website, ''), COALESCE(instagram, ''),
+	COALESCE(facebook, ''), COALESCE(company_registration_id, ''), COALESCE(business_status, ''),
+	COALESCE(user_ratings_total, 0), COALESCE(company_size, '')
+`
+
+type scorer struct {
+	db     *sql.DB
+	config ScoringConfig
+}
+
+// Pontua os leads novos ou alterados depois da última pontuação. O update da
+// nota não mexe em updated_at, então um lead só volta aqui quando a api o altera.
+func (s *scorer) scorePending() (int, error) {
+	scored := 0
+	for {
+		leads, err := s.loadLeads(`
+			SELECT `+scoreColumns+` FROM leads
+			WHERE scored_at IS NULL OR updated_at > scored_at
+			ORDER BY id
+			LIMIT $1
+		`, scoreBatchSize)
+		if err != nil {
+			return scored, err
+		}
+		if err := s.save(leads); err != nil {
+			return scored, err
+		}
+		scored += len(leads)
+		if len(leads) < scoreBatchSize {
+			return scored, nil
+		}
+	}
+}
+
+// Pontua a tabela inteira de novo, para refletir mudanças nos pesos
+func (s *scorer) rescoreAll() (int, error) {
+	scored := 0
+	lastID := "00000000-0000-0000-0000-000000000000"
+	for {
+		leads, err := s.loadLeads(`
+			SELECT `+scoreColumns+` FROM leads
+			WHERE id > $1::uuid
+			ORDER BY id
+			LIMIT $2
+		`, lastID, scoreBatchSize)
+		if err != nil {
+			return scored, err
+		}
+		if len(leads) == 0 {
+			return scored, nil
+		}
+		if err := s.save(leads); err != nil {
+			return scored, err
+		}
+		scored += len(leads)
+		lastID = leads[len(leads)-1].ID
+	}
+}
+
+func (s *scorer) loadLeads(query string, args ...interface{}) ([]Lead, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leads: %v", err)
+	}
+	defer rows.Close()
+
+	var leads []Lead
+	for rows.Next() {
+		var lead Lead
+		err := rows.Scan(&lead.ID, &lead.BusinessName, &lead.Address, &lead.Phone,
+			&lead.Whatsapp, &lead.Email, &lead.Website, &lead.Instagram,
+			&lead.Facebook, &lead.CNPJ, &lead.BusinessStatus,
+			&lead.UserRatingsTotal, &lead.CompanySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lead: %v", err)
+		}
+		leads = append(leads, lead)
+	}
+	return leads, rows.Err()
+}
+
+// Grava nota, faixa e campos preenchidos de um lote numa transação. scored_at
+// nunca fica antes de updated_at, mesmo com o relógio da api adiantado.
+func (s *scorer) save(leads []Lead) error {
+	if len(leads) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		UPDATE leads
+		SET quality = $1, fields_filled = $2, quality_score = $3,
+			scored_at = GREATEST(now(), updated_at)
+		WHERE id = $4::uuid
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare lead update: %v", err)
+	}
+	defer stmt.Close()
+
+	for i := range leads {
+		s.config.Score(&leads[i])
+		_, err := stmt.Exec(leads[i].Quality, leads[i].FieldsFilled, leads[i].Score, leads[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to update lead %s: %v", leads[i].ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Pontua os leads novos a cada newInterval e a tabela inteira a cada
+// rescoreInterval (0 desliga a repontuação)
+func (s *scorer) Run(newInterval time.Duration, rescoreInterval time.Duration) {
+	s.runRescore()
+
+	pending := time.NewTicker(newInterval)
+	defer pending.Stop()
+
+	var rescore <-chan time.Time
+	if rescoreInterval > 0 {
+		ticker := time.NewTicker(rescoreInterval)
+		defer ticker.Stop()
+		rescore = ticker.C
+	}
+
+	for {
+		select {
+		case <-pending.C:
+			count, err := s.scorePending()
+			if err != nil {
+				log.Printf("Erro ao pontuar leads novos: %v", err)
+			}
+			if count > 0 {
+				log.Printf("%d leads novos ou alterados pontuados", count)
+			}
+		case <-rescore:
+			s.runRescore()
+		}
+	}
+}
+
+func (s *scorer) runRescore() {
+	start := time.Now()
+	count, err := s.rescoreAll()
+	if err != nil {
+		log.Printf("Erro ao repontuar leads: %v", err)
+		return
+	}
+	log.Printf("%d leads repontuados em %s", count, time.Since(start).Round(time.Millisecond))
+}
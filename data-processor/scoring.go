@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pesos das regras de pontuação. A nota do lead é a soma dos pesos das regras
+// atendidas, convertida para 0-100 sobre a soma de todos os pesos.
+type ScoringConfig struct {
+	Weights struct {
+		Phone        float64 `json:"phone"`
+		Whatsapp     float64 `json:"whatsapp"` // só é salvo na api quando verificado
+		Email        float64 `json:"email"`
+		Website      float64 `json:"website"`
+		CNPJ         float64 `json:"cnpj"`
+		ActiveStatus float64 `json:"active_status"`
+		RatingsCount float64 `json:"ratings_count"`
+		CompanySize  float64 `json:"company_size"`
+	} `json:"weights"`
+
+	// Avaliações a partir das quais ratings_count vale o peso inteiro; abaixo
+	// disso vale proporcionalmente
+	RatingsTarget int `json:"ratings_target"`
+
+	// Fração do peso de company_size por porte (em maiúsculas, como vem do CNPJ)
+	CompanySizes map[string]float64 `json:"company_sizes"`
+
+	// Situações consideradas ativas (Google Places e Receita)
+	ActiveStatuses []string `json:"active_statuses"`
+
+	// Faixas por nota mínima: o lead fica na de maior mínimo que atingir
+	Tiers []ScoringTier `json:"tiers"`
+}
+
+type ScoringTier struct {
+	Name     string  `json:"name"`
+	MinScore float64 `json:"min_score"`
+}
+
+func defaultScoringConfig() ScoringConfig {
+	var config ScoringConfig
+	config.Weights.Phone = 15
+	config.Weights.Whatsapp = 20
+	config.Weights.Email = 15
+	config.Weights.Website = 10
+	config.Weights.CNPJ = 15
+	config.Weights.ActiveStatus = 10
+	config.Weights.RatingsCount = 10
+	config.Weights.CompanySize = 5
+	config.RatingsTarget = 50
+	config.CompanySizes = map[string]float64{
+		"DEMAIS":                   1,
+		"EMPRESA DE PEQUENO PORTE": 0.7,
+		"MICRO EMPRESA":            0.4,
+	}
+	config.ActiveStatuses = []string{"OPERATIONAL", "ATIVA"}
+	config.Tiers = []ScoringTier{
+		{Name: "high", MinScore: 70},
+		{Name: "medium", MinScore: 40},
+		{Name: "low", MinScore: 0},
+	}
+	return config
+}
+
+// Lê a configuração do arquivo em SCORING_CONFIG; campos ausentes ficam com o padrão
+func loadScoringConfig() (ScoringConfig, error) {
+	config := defaultScoringConfig()
+	path := os.Getenv("SCORING_CONFIG")
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read scoring config: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse scoring config: %v", err)
+	}
+	if len(config.Tiers) == 0 {
+		return config, fmt.Errorf("scoring config must have at least one tier")
+	}
+	// tier() percorre as faixas da maior nota mínima para a menor
+	sort.SliceStable(config.Tiers, func(i, j int) bool {
+		return config.Tiers[i].MinScore > config.Tiers[j].MinScore
+	})
+	return config, nil
+}
+
+// Campos do lead usados na pontuação
+type Lead struct {
+	ID               string
+	BusinessName     string
+	Address          string
+	Phone            string
+	Whatsapp         string
+	Email            string
+	Website          string
+	Instagram        string
+	Facebook         string
+	CNPJ             string
+	BusinessStatus   string
+	UserRatingsTotal int
+	CompanySize      string
+	Quality          string
+	FieldsFilled     int
+	Score            float64
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	nonDigits    = regexp.MustCompile(`\D`)
+)
+
+// Calcula nota, faixa e quantidade de campos preenchidos
+func (config ScoringConfig) Score(lead *Lead) {
+	weights := config.Weights
+	total := weights.Phone + weights.Whatsapp + weights.Email + weights.Website +
+		weights.CNPJ + weights.ActiveStatus + weights.RatingsCount + weights.CompanySize
+
+	points := 0.0
+	if len(nonDigits.ReplaceAllString(lead.Phone, "")) >= 8 {
+		points += weights.Phone
+	}
+	if strings.TrimSpace(lead.Whatsapp) != "" {
+		points += weights.Whatsapp
+	}
+	if emailPattern.MatchString(strings.TrimSpace(lead.Email)) {
+		points += weights.Email
+	}
+	if strings.TrimSpace(lead.Website) != "" {
+		points += weights.Website
+	}
+	if len(nonDigits.ReplaceAllString(lead.CNPJ, "")) == 14 {
+		points += weights.CNPJ
+	}
+	if config.isActive(lead.BusinessStatus) {
+		points += weights.ActiveStatus
+	}
+	if config.RatingsTarget > 0 && lead.UserRatingsTotal > 0 {
+		points += weights.RatingsCount * minFloat(1, float64(lead.UserRatingsTotal)/float64(config.RatingsTarget))
+	}
+	points += weights.CompanySize * config.CompanySizes[strings.ToUpper(strings.TrimSpace(lead.CompanySize))]
+
+	lead.Score = 0
+	if total > 0 {
+		lead.Score = roundScore(points / total * 100)
+	}
+	lead.Quality = config.tier(lead.Score)
+	lead.FieldsFilled = fieldsFilled(lead)
+}
+
+func (config ScoringConfig) isActive(status string) bool {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	for _, active := range config.ActiveStatuses {
+		if status == strings.ToUpper(active) {
+			return true
+		}
+	}
+	return false
+}
+
+func (config ScoringConfig) tier(score float64) string {
+	for _, tier := range config.Tiers {
+		if score >= tier.MinScore {
+			return tier.Name
+		}
+	}
+	return config.Tiers[len(config.Tiers)-1].Name
+}
+
+// Campos de contato e cadastro preenchidos
+func fieldsFilled(lead *Lead) int {
+	count := 0
+	for _, value := range []string{
+		lead.BusinessName, lead.Address, lead.Phone, lead.Whatsapp, lead.Email,
+		lead.Website, lead.Instagram, lead.Facebook, lead.CNPJ, lead.CompanySize,
+	} {
+		if strings.TrimSpace(value) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*100+0.5)) / 100
+}
+
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScoreWeights(t *testing.T) {
+	config := defaultScoringConfig()
+
+	tests := []struct {
+		name  string
+		lead  Lead
+		score float64
+	}{
+		{name: "empty lead", lead: Lead{}, score: 0},
+		{name: "phone", lead: Lead{Phone: "(11) 3456-7890"}, score: 15},
+		{name: "short phone", lead: Lead{Phone: "3456"}, score: 0},
+		{name: "whatsapp", lead: Lead{Whatsapp: "+5511987654321"}, score: 20},
+		{name: "email", lead: Lead{Email: "contato@padaria.com.br"}, score: 15},
+		{name: "invalid email", lead: Lead{Email: "contato"}, score: 0},
+		{name: "website", lead: Lead{Website: "https://padaria.com.br"}, score: 10},
+		{name: "cnpj", lead: Lead{CNPJ: "12.345.678/0001-90"}, score: 15},
+		{name: "active status", lead: Lead{BusinessStatus: "operational"}, score: 10},
+		{name: "full ratings count", lead: Lead{UserRatingsTotal: 80}, score: 10},
+		{name: "partial ratings count", lead: Lead{UserRatingsTotal: 25}, score: 5},
+		{name: "company size", lead: Lead{CompanySize: "DEMAIS"}, score: 5},
+		{name: "company size fraction", lead: Lead{CompanySize: "micro empresa"}, score: 2},
+		{name: "unknown company size", lead: Lead{CompanySize: "OUTRO"}, score: 0},
+		{
+			name: "complete lead",
+			lead: Lead{
+				Phone: "(11) 3456-7890", Whatsapp: "+5511987654321", Email: "contato@padaria.com.br",
+				Website: "https://padaria.com.br", CNPJ: "12345678000190", BusinessStatus: "ATIVA",
+				UserRatingsTotal: 50, CompanySize: "DEMAIS",
+			},
+			score: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lead := tt.lead
+			config.Score(&lead)
+			if lead.Score != tt.score {
+				t.Errorf("expected score %v, got %v", tt.score, lead.Score)
+			}
+		})
+	}
+}
+
+func TestScoreTierBoundaries(t *testing.T) {
+	config := defaultScoringConfig()
+
+	tests := []struct {
+		score float64
+		tier  string
+	}{
+		{score: 100, tier: "high"},
+		{score: 70, tier: "high"},
+		{score: 69.99, tier: "medium"},
+		{score: 40, tier: "medium"},
+		{score: 39.99, tier: "low"},
+		{score: 0, tier: "low"},
+	}
+	for _, tt := range tests {
+		if tier := config.tier(tt.score); tier != tt.tier {
+			t.Errorf("expected tier %s for score %v, got %s", tt.tier, tt.score, tier)
+		}
+	}
+}
+
+func TestLoadScoringConfigSortsTiers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	config := `{"tiers": [
+		{"name": "low", "min_score": 0},
+		{"name": "high", "min_score": 70},
+		{"name": "medium", "min_score": 40}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCORING_CONFIG", path)
+
+	loaded, err := loadScoringConfig()
+	if err != nil {
+		t.Fatalf("loadScoringConfig: %v", err)
+	}
+	if tier := loaded.tier(85); tier != "high" {
+		t.Errorf("expected tier high for score 85, got %s", tier)
+	}
+	if tier := loaded.tier(50); tier != "medium" {
+		t.Errorf("expected tier medium for score 50, got %s", tier)
+	}
+	if tier := loaded.tier(10); tier != "low" {
+		t.Errorf("expected tier low for score 10, got %s", tier)
+	}
+	// Pesos ausentes no arquivo ficam com o padrão
+	if loaded.Weights.Whatsapp != 20 {
+		t.Errorf("expected default whatsapp weight, got %v", loaded.Weights.Whatsapp)
+	}
+}
+
+func TestLoadScoringConfigRequiresTiers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	if err := os.WriteFile(path, []byte(`{"tiers": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCORING_CONFIG", path)
+
+	if _, err := loadScoringConfig(); err == nil {
+		t.Error("expected an error for a config without tiers")
+	}
+}
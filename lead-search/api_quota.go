@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var googleAPICalls = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "leadsearch_google_api_calls_total",
+		Help: "Total de chamadas às APIs do Google por SKU",
+	},
+	[]string{"sku"},
+)
+
+var googleAPISpend = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "leadsearch_google_estimated_spend_usd",
+		Help: "Gasto estimado do dia (UTC) com as APIs do Google, em USD",
+	},
+)
+
+// Monta o limitador compartilhado a partir do ambiente:
+//
+//	GOOGLE_DAILY_BUDGET=50        orçamento diário em USD (0 = sem limite)
+//	GOOGLE_BUDGET_MODE=block      espera o dia seguinte em vez de falhar as buscas
+//	GOOGLE_QPS_TEXT_SEARCH=5      chamadas por segundo de cada SKU
+//	GOOGLE_COST_PLACE_DETAILS=0.017  custo estimado por chamada de cada SKU
+func newAPILimiter(db *sql.DB) *googleplaces.Limiter {
+	config := googleplaces.LimiterConfig{
+		QPS:           make(map[string]float64),
+		Costs:         make(map[string]float64),
+		DailyBudget:   getEnvFloat("GOOGLE_DAILY_BUDGET", 0),
+		BlockOnBudget: os.Getenv("GOOGLE_BUDGET_MODE") == "block",
+	}
+	for sku, qps := range googleplaces.DefaultSKUQPS {
+		config.QPS[sku] = getEnvFloat("GOOGLE_QPS_"+strings.ToUpper(sku), qps)
+	}
+	for sku, cost := range googleplaces.DefaultSKUCosts {
+		config.Costs[sku] = getEnvFloat("GOOGLE_COST_"+strings.ToUpper(sku), cost)
+	}
+
+	// O gasto de hoje vem do ledger para que reiniciar o serviço não zere o orçamento
+	spent, err := repository.GetAPISpendForDay(db, time.Now())
+	if err != nil {
+		log.Printf("Erro ao carregar o gasto do dia com a API do Google: %v", err)
+	}
+	googleAPISpend.Set(spent)
+
+	if config.DailyBudget > 0 {
+		log.Printf("Orçamento diário da API do Google: %.2f USD (%.2f USD já gastos hoje)", config.DailyBudget, spent)
+	}
+	return googleplaces.NewLimiter(config, spent)
+}
+
+// Provider usado por uma busca: passa pelo limitador e registra cada chamada
+// no ledger de custos da linha de search_progress
+func meteredProviderFor(db *sql.DB, provider googleplaces.PlacesProvider, limiter *googleplaces.Limiter, progressID int64) googleplaces.PlacesProvider {
+	return googleplaces.NewMeteredProvider(provider, limiter, func(sku string, cost float64) {
+		googleAPICalls.WithLabelValues(sku).Inc()
+		if limiter != nil {
+			googleAPISpend.Set(limiter.SpentToday())
+		}
+
+		err := repository.RecordAPICall(db, progressID, sku, cost, time.Now())
+		if err != nil {
+			log.Printf("Erro ao registrar a chamada %s da busca %d: %v", sku, progressID, err)
+		}
+	})
+}
+
+func getEnvFloat(name string, defaultValue float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %.3f", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+)
+
+type batchSearchRequest struct {
+	CategoryIDs []int `json:"category_ids"`
+	StateID     int   `json:"state_id"`
+	CityID      int   `json:"city_id"`
+	DistrictID  int   `json:"district_id"`
+	Radius      int   `json:"radius"`
+	MaxResults  int   `json:"max_results"`
+	MaxPages    int   `json:"max_pages"`
+}
+
+type batchSearchJob struct {
+	JobID      int64  `json:"job_id"`
+	CategoryID string `json:"category_id"`
+	ZipcodeID  int    `json:"zipcode_id"`
+}
+
+type batchSearchResponse struct {
+	Scheduled []batchSearchJob `json:"scheduled"`
+	Skipped   int              `json:"skipped"`
+	Errors    []string         `json:"errors,omitempty"`
+}
+
+func batchSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, queue *searchQueue) {
+	startTime := time.Now()
+	totalRequests.WithLabelValues("/searches/batch", r.Method).Inc()
+
+	if r.Method != http.MethodPost {
+		totalErrors.WithLabelValues("/searches/batch", "invalid_method").Inc()
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req batchSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		totalErrors.WithLabelValues("/searches/batch", "invalid_body").Inc()
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.CategoryIDs) == 0 || req.Radius <= 0 {
+		totalErrors.WithLabelValues("/searches/batch", "missing_params").Inc()
+		http.Error(w, "category_ids and radius are required", http.StatusBadRequest)
+		return
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultMaxResults
+	}
+	if req.MaxPages <= 0 || req.MaxPages > googleplaces.MaxTextSearchPages {
+		req.MaxPages = googleplaces.MaxTextSearchPages
+	}
+
+	for _, categoryID := range req.CategoryIDs {
+		if _, err := repository.GetCategoryNameByID(db, strconv.Itoa(categoryID)); err != nil {
+			totalErrors.WithLabelValues("/searches/batch", "invalid_category").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	zipcodeIDs, err := repository.ListZipcodeIDs(db, req.StateID, req.CityID, req.DistrictID)
+	if err != nil {
+		totalErrors.WithLabelValues("/searches/batch", "list_zipcodes").Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(zipcodeIDs) == 0 {
+		totalErrors.WithLabelValues("/searches/batch", "no_zipcodes").Inc()
+		http.Error(w, "No zipcodes found for the given location", http.StatusNotFound)
+		return
+	}
+
+	response := batchSearchResponse{Scheduled: []batchSearchJob{}}
+	var jobs []searchJob
+
+	for _, category := range req.CategoryIDs {
+		categoryID := strconv.Itoa(category)
+
+		scheduled, err := repository.GetScheduledZipcodeIDs(db, categoryID)
+		if err != nil {
+			totalErrors.WithLabelValues("/searches/batch", "list_searched").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, zipcodeID := range zipcodeIDs {
+			if scheduled[zipcodeID] {
+				response.Skipped++
+				continue
+			}
+
+			job, err := createSearchJob(db, searchJob{
+				Mode:       searchModeRadius,
+				CategoryID: categoryID,
+				ZipcodeID:  zipcodeID,
+				Radius:     req.Radius,
+				MaxResults: req.MaxResults,
+				MaxPages:   req.MaxPages,
+			})
+			if err != nil {
+				log.Printf("Erro ao agendar busca da categoria %s no zipcode %d: %v", categoryID, zipcodeID, err)
+				response.Errors = append(response.Errors, fmt.Sprintf("category %s, zipcode %d: %v", categoryID, zipcodeID, err))
+				continue
+			}
+
+			jobs = append(jobs, job)
+			response.Scheduled = append(response.Scheduled, batchSearchJob{
+				JobID:      job.ProgressID,
+				CategoryID: categoryID,
+				ZipcodeID:  zipcodeID,
+			})
+		}
+	}
+
+	// O lote pode ser maior que a fila; os jobs já estão gravados como queued
+	// e entram na fila à medida que os workers liberam espaço
+	go func() {
+		for _, job := range jobs {
+			queue.EnqueueWait(job)
+		}
+		log.Printf("Lote de %d buscas enfileirado", len(jobs))
+	}()
+
+	log.Printf("Lote agendado: %d buscas, %d ignoradas, %d erros", len(response.Scheduled), response.Skipped, len(response.Errors))
+	processingDuration.WithLabelValues("/searches/batch").Observe(time.Since(startTime).Seconds())
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// Grava a busca em search_progress como queued e marca a categoria e a
+// localização como em andamento. O job devolvido já tem o ProgressID.
+func createSearchJob(db *sql.DB, job searchJob) (searchJob, error) {
+	locationInfo, err := resolveSearchLocation(db, job)
+	if err != nil {
+		return job, fmt.Errorf("failed to get location info: %v", err)
+	}
+
+	progressID, err := repository.InsertSearchProgress(db, searchProgressFor(job, locationInfo))
+	if err != nil {
+		return job, err
+	}
+	job.ProgressID = progressID
+
+	markSearchScheduled(db, job)
+	return job, nil
+}
+
+func markSearchScheduled(db *sql.DB, job searchJob) {
+	if err := repository.MarkCategoryInProgress(db, job.CategoryID); err != nil {
+		log.Printf("Erro ao atualizar o status da categoria %s: %v", job.CategoryID, err)
+	}
+	if job.ZipcodeID != 0 {
+		if err := repository.MarkZipcodeInProgress(db, job.ZipcodeID); err != nil {
+			log.Printf("Erro ao atualizar o status do zipcode %d: %v", job.ZipcodeID, err)
+		}
+	}
+}
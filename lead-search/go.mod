@@ -6,7 +6,7 @@ require (
 	github.com/PuerkitoBio/goquery v1.10.0
 	github.com/go-resty/resty/v2 v2.15.1
 	github.com/joho/godotenv v1.5.1
-
+	github.com/robfig/cron/v3 v3.0.1
 )
 
 require (
@@ -27,5 +27,7 @@ require (
 	github.com/prometheus/client_golang v1.20.5
 	github.com/streadway/amqp v1.1.0
 	golang.org/x/net v0.29.0 // indirect
-
+	shared v0.0.0
 )
+
+replace shared => ../shared
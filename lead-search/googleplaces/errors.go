@@ -0,0 +1,64 @@
+package googleplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Erro de uma chamada às APIs do Google. Retryable indica se vale tentar de
+// novo (cota, instabilidade, timeout) ou se a resposta não vai mudar.
+type APIError struct {
+	Op         string // geocode, search ou details
+	Status     string // status da API (OVER_QUERY_LIMIT, NOT_FOUND...) quando houver
+	HTTPStatus int
+	Message    string
+	Retryable  bool
+	Err        error // erro de transporte, quando a requisição nem chegou a ter resposta
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	case e.Status != "":
+		return fmt.Sprintf("%s: API error: %s, message: %s", e.Op, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Op, e.HTTPStatus, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Status que costumam passar sozinhos: cota por segundo, erros internos e
+// indisponibilidade. A API nova usa os códigos do gRPC.
+var retryableStatuses = map[string]bool{
+	"OVER_QUERY_LIMIT":   true,
+	"UNKNOWN_ERROR":      true,
+	"RESOURCE_EXHAUSTED": true,
+	"UNAVAILABLE":        true,
+	"INTERNAL":           true,
+	"DEADLINE_EXCEEDED":  true,
+}
+
+func newStatusError(op string, status string, message string) *APIError {
+	return &APIError{Op: op, Status: status, Message: message, Retryable: retryableStatuses[status]}
+}
+
+func newHTTPError(op string, code int, status string, message string) *APIError {
+	retryable := code == http.StatusTooManyRequests || code >= http.StatusInternalServerError || retryableStatuses[status]
+	return &APIError{Op: op, Status: status, HTTPStatus: code, Message: message, Retryable: retryable}
+}
+
+// Falhas de rede e timeouts são tentadas de novo; uma chamada cancelada pelo
+// ctx não
+func newTransportError(op string, err error) *APIError {
+	return &APIError{Op: op, Err: err, Retryable: !errors.Is(err, context.Canceled)}
+}
+
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
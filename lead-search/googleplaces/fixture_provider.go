@@ -0,0 +1,97 @@
+package googleplaces
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reproduz respostas JSON gravadas da Places API, sem acesso à rede.
+// Os arquivos ficam em Dir com o mesmo layout usado por GoogleProvider.RecordDir:
+//
+//	geocode/<endereço>.json
+//	textsearch/<query>_<location>_<radius>.json   (primeira página)
+//	textsearch/page_<next_page_token>.json        (páginas seguintes)
+//	details/<place_id>.json
+type FixtureProvider struct {
+	Dir string
+}
+
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{Dir: dir}
+}
+
+func (p *FixtureProvider) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	body, err := p.read(geocodeFixturePath(address))
+	if err != nil {
+		return GeocodeResult{}, err
+	}
+	result, err := parseGeocodeResponse(address, body)
+	result.Source = "fixture"
+	return result, err
+}
+
+func (p *FixtureProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	body, err := p.read(searchFixturePath(req))
+	if err != nil {
+		return SearchPage{}, err
+	}
+	return parseTextSearchResponse(body)
+}
+
+func (p *FixtureProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	body, err := p.read(detailsFixturePath(placeID))
+	if err != nil {
+		return nil, err
+	}
+	return parseDetailsResponse(placeID, body)
+}
+
+func (p *FixtureProvider) read(path string) ([]byte, error) {
+	body, err := os.ReadFile(filepath.Join(p.Dir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("fixture not found: %s", path)
+		}
+		return nil, fmt.Errorf("error reading fixture %s: %v", path, err)
+	}
+	return body, nil
+}
+
+func geocodeFixturePath(address string) string {
+	return filepath.Join("geocode", fixtureName(address)+".json")
+}
+
+func searchFixturePath(req SearchRequest) string {
+	if req.PageToken != "" {
+		return filepath.Join("textsearch", "page_"+fixtureName(req.PageToken)+".json")
+	}
+	name := fmt.Sprintf("%s_%s_%d", fixtureName(req.Query), fixtureName(req.Location), req.Radius)
+	return filepath.Join("textsearch", name+".json")
+}
+
+func detailsFixturePath(placeID string) string {
+	return filepath.Join("details", fixtureName(placeID)+".json")
+}
+
+// Troca tudo que não for letra, dígito, ponto ou hífen por _, para que
+// endereços e coordenadas virem nomes de arquivo válidos
+func fixtureName(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, value)
+}
+
+func writeFixture(dir string, path string, body []byte) error {
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath, body, 0644)
+}
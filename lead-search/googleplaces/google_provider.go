@@ -0,0 +1,128 @@
+package googleplaces
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const DefaultBaseURL = "https://maps.googleapis.com/maps/api"
+
+const (
+	defaultHTTPTimeout  = 30 * time.Second
+	geocodeSourceGoogle = "google_geocoding"
+)
+
+// Provider da Places API legada (textsearch/details em JSON)
+type GoogleProvider struct {
+	APIKey  string
+	BaseURL string
+	Client  *resty.Client
+
+	// Opcional: grava cada resposta no formato do FixtureProvider
+	RecordDir string
+}
+
+// baseURL vazio usa a API do Google; client nil cria um cliente compartilhado
+// por todas as chamadas deste provider
+func NewGoogleProvider(apiKey string, baseURL string, client *resty.Client) *GoogleProvider {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if client == nil {
+		client = NewHTTPClient()
+	}
+	return &GoogleProvider{
+		APIKey:  apiKey,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  client,
+	}
+}
+
+func NewHTTPClient() *resty.Client {
+	return resty.New().SetTimeout(defaultHTTPTimeout)
+}
+
+func (p *GoogleProvider) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	body, err := p.get(ctx, "geocode", "/geocode/json", map[string]string{
+		"address": address,
+		"key":     p.APIKey,
+	})
+	if err != nil {
+		return GeocodeResult{}, err
+	}
+
+	p.record(geocodeFixturePath(address), body)
+	result, err := parseGeocodeResponse(address, body)
+	result.Source = geocodeSourceGoogle
+	return result, err
+}
+
+func (p *GoogleProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	params := map[string]string{
+		"query":    req.Query,
+		"location": req.Location,
+		"radius":   fmt.Sprintf("%d", req.Radius),
+		"key":      p.APIKey,
+	}
+	if req.PageToken != "" {
+		params["pagetoken"] = req.PageToken
+	}
+
+	body, err := p.get(ctx, "search", "/place/textsearch/json", params)
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	page, err := parseTextSearchResponse(body)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	// Respostas de token ainda não disponível não são gravadas, só a definitiva
+	if page.Status != StatusInvalidRequest {
+		p.record(searchFixturePath(req), body)
+	}
+	return page, nil
+}
+
+func (p *GoogleProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	body, err := p.get(ctx, "details", "/place/details/json", map[string]string{
+		"place_id": placeID,
+		"key":      p.APIKey,
+		"fields":   "name,formatted_address,international_phone_number,website,rating,address_components,editorial_summary",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.record(detailsFixturePath(placeID), body)
+	return parseDetailsResponse(placeID, body)
+}
+
+func (p *GoogleProvider) get(ctx context.Context, op string, path string, params map[string]string) ([]byte, error) {
+	resp, err := p.Client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		Get(p.BaseURL + path)
+	if err != nil {
+		return nil, newTransportError(op, err)
+	}
+
+	if !resp.IsSuccess() {
+		return nil, newHTTPError(op, resp.StatusCode(), "", resp.Status())
+	}
+	return resp.Body(), nil
+}
+
+func (p *GoogleProvider) record(path string, body []byte) {
+	if p.RecordDir == "" {
+		return
+	}
+	if err := writeFixture(p.RecordDir, path, body); err != nil {
+		log.Printf("Erro ao gravar a resposta em %s: %v", path, err)
+	}
+}
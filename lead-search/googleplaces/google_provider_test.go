@@ -0,0 +1,37 @@
+package googleplaces
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Uma resposta gravada pelo GoogleProvider deve ser reproduzida igual pelo FixtureProvider
+func TestGoogleProviderRecordsFixtures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/place/textsearch/json" || r.URL.Query().Get("query") != "padaria" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"results":[{"name":"Padaria","place_id":"abc"}],"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	provider := NewGoogleProvider("test-key", server.URL, nil)
+	provider.RecordDir = dir
+
+	req := SearchRequest{Query: "padaria", Location: "-23.5,-46.6", Radius: 500}
+	page, err := provider.Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	replayed, err := NewFixtureProvider(dir).Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("fixture Search: %v", err)
+	}
+	if page.Status != StatusOK || len(replayed.Results) != 1 || replayed.Results[0].PlaceID != "abc" {
+		t.Errorf("unexpected replayed page: %+v", replayed)
+	}
+}
@@ -1,19 +1,32 @@
 package googleplaces
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/go-resty/resty/v2"
+	"lead-search/repository"
 )
 
 type Service struct {
-	APIKey string
+	APIKey   string
+	Provider PlacesProvider
+
+	// Espera antes de pedir a próxima página da Text Search
+	PageTokenDelay time.Duration
+
+	// Opcional: guarda o next_page_token de cada consulta para permitir retomar
+	ProgressStore repository.QueryProgressStore
+
+	// Opcional: evita geocodificar o mesmo CEP várias vezes. Com TTL zero as
+	// entradas nunca expiram.
+	GeocodeCache    repository.GeocodeCacheStore
+	GeocodeCacheTTL time.Duration
 }
 
 type TokenStore struct {
@@ -34,443 +47,329 @@ type PlaceResult struct {
 	Types             []string `json:"types"`
 }
 
+// Service sobre a Places API do Google com a URL padrão
 func NewService(apiKey string) *Service {
-	return &Service{APIKey: apiKey}
+	service := NewServiceWithProvider(NewGoogleProvider(apiKey, "", nil))
+	service.APIKey = apiKey
+	return service
 }
 
-func (s *Service) GeocodeZip(zipCode string) (string, error) {
-	log.Printf("Buscando coordenadas para o zipCode: %s", zipCode)
-
-	client := resty.New()
+func NewServiceWithProvider(provider PlacesProvider) *Service {
+	return &Service{
+		Provider:       provider,
+		PageTokenDelay: nextPageTokenDelay,
+	}
+}
 
-	geocodeURL := "https://maps.googleapis.com/maps/api/geocode/json"
-	resp, err := client.R().
-		SetQueryParams(map[string]string{
-			"address": zipCode,
-			"key":     s.APIKey,
-		}).
-		Get(geocodeURL)
+// Geocodifica o CEP, consultando antes o GeocodeCache quando configurado.
+// O bool indica se as coordenadas vieram do cache.
+func (s *Service) GeocodeZip(ctx context.Context, zipCode string) (string, bool, error) {
+	key := NormalizeZipCode(zipCode)
 
-	if err != nil {
-		return "", fmt.Errorf("error connecting to Geocoding API: %v", err)
+	if s.GeocodeCache != nil && key != "" {
+		entry, err := s.GeocodeCache.LoadGeocode(key)
+		if err != nil {
+			log.Printf("Erro ao carregar o cache de geocodificação do CEP %s: %v", key, err)
+		} else if entry != nil && (s.GeocodeCacheTTL <= 0 || time.Since(entry.UpdatedAt) < s.GeocodeCacheTTL) {
+			log.Printf("Coordenadas do CEP %s encontradas no cache", key)
+			return LatLng{Lat: entry.Lat, Lng: entry.Lng}.String(), true, nil
+		}
 	}
 
-	var result struct {
-		Results []struct {
-			Geometry struct {
-				Location struct {
-					Lat float64 `json:"lat"`
-					Lng float64 `json:"lng"`
-				} `json:"location"`
-			} `json:"geometry"`
-		} `json:"results"`
-		Status       string `json:"status"`
-		ErrorMessage string `json:"error_message"`
-	}
+	log.Printf("Buscando coordenadas para o zipCode: %s", zipCode)
 
-	err = json.Unmarshal(resp.Body(), &result)
+	result, err := s.Provider.Geocode(ctx, zipCode)
 	if err != nil {
-		return "", fmt.Errorf("error parsing geocode response: %v", err)
+		return "", false, err
 	}
-
-	if result.Status != "OK" {
-		return "", fmt.Errorf("geocoding API error: %s, message: %s", result.Status, result.ErrorMessage)
+	location := result.Location
+
+	if s.GeocodeCache != nil && key != "" {
+		err = s.GeocodeCache.SaveGeocode(repository.GeocodeCacheEntry{
+			ZipCode:          key,
+			Lat:              location.Lat,
+			Lng:              location.Lng,
+			FormattedAddress: result.FormattedAddress,
+			Source:           result.Source,
+		})
+		if err != nil {
+			log.Printf("Erro ao salvar o CEP %s no cache de geocodificação: %v", key, err)
+		}
 	}
 
-	if len(result.Results) > 0 {
-		lat := result.Results[0].Geometry.Location.Lat
-		lng := result.Results[0].Geometry.Location.Lng
-		return fmt.Sprintf("%f,%f", lat, lng), nil
-	}
+	return location.String(), false, nil
+}
 
-	return "", fmt.Errorf("no results found for zipCode: %s", zipCode)
+// Mantém só os dígitos do CEP, para que "01310-100" e "01310100" usem a mesma entrada do cache
+func NormalizeZipCode(zipCode string) string {
+	var digits strings.Builder
+	for _, r := range zipCode {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
 }
 
 func generateQueryKey(query string, location string, radius int) string {
 	return fmt.Sprintf("%s|%s|%d", query, location, radius)
 }
 
-func loadToken(queryKey string) (string, int, int, error) {
-
-	const directory = "/app/lead-search"
-	const filePath = directory + "/next_page_tokens.json"
-
-	var tokenStore TokenStore
-
+// Importa uma única vez o antigo next_page_tokens.json para o store em
+// SQLite. Depois de importado o arquivo é renomeado com o sufixo .migrated.
+func MigrateTokenFile(store repository.QueryProgressStore, filePath string) error {
 	file, err := os.ReadFile(filePath)
 	if err != nil {
-
 		if os.IsNotExist(err) {
-			log.Printf("Arquivo %s não encontrado. Criando um novo...", filePath)
-
-			tokenStore = TokenStore{QueryTokens: make(map[string]map[string]interface{})}
-
-			if _, dirErr := os.Stat(directory); os.IsNotExist(dirErr) {
-				log.Printf("Diretório %s não encontrado. Criando...", directory)
-				dirErr = os.MkdirAll(directory, os.ModePerm)
-				if dirErr != nil {
-					return "", 0, 0, fmt.Errorf("erro ao criar o diretório %s: %v", directory, dirErr)
-				}
-			}
-
-			tokenStoreBytes, jsonErr := json.MarshalIndent(tokenStore, "", "  ")
-			if jsonErr != nil {
-				return "", 0, 0, fmt.Errorf("erro ao serializar tokens: %v", jsonErr)
-			}
-			err = os.WriteFile(filePath, tokenStoreBytes, 0644)
-			if err != nil {
-				return "", 0, 0, fmt.Errorf("erro ao criar o arquivo JSON vazio: %v", err)
-			}
-
-			return "", 0, 0, nil
+			return nil
 		}
-		return "", 0, 0, fmt.Errorf("erro ao ler o arquivo JSON: %v", err)
+		return fmt.Errorf("erro ao ler o arquivo JSON: %v", err)
 	}
 
-	if len(strings.TrimSpace(string(file))) == 0 {
-		tokenStore = TokenStore{QueryTokens: make(map[string]map[string]interface{})}
-	} else {
-
+	var tokenStore TokenStore
+	if len(strings.TrimSpace(string(file))) > 0 {
 		err = json.Unmarshal(file, &tokenStore)
 		if err != nil {
-			return "", 0, 0, fmt.Errorf("erro ao fazer parse do arquivo JSON: %v", err)
+			return fmt.Errorf("erro ao fazer parse do arquivo JSON: %v", err)
 		}
 	}
 
-	if queryData, exists := tokenStore.QueryTokens[queryKey]; exists {
+	migrated := 0
+	for queryKey, queryData := range tokenStore.QueryTokens {
+		query, location, radius, ok := parseQueryKey(queryKey)
+		if !ok {
+			log.Printf("Chave inválida no arquivo de tokens, ignorando: %s", queryKey)
+			continue
+		}
 
-		token, tokenOk := queryData["next_page_token"].(string)
-		pagesFetched, pagesOk := queryData["pages_fetched"].(float64)
-		leadsExtracted, leadsOk := queryData["leads_extracted"].(float64)
+		token, _ := queryData["next_page_token"].(string)
+		pagesFetched, _ := queryData["pages_fetched"].(float64)
+		leadsExtracted, _ := queryData["leads_extracted"].(float64)
+		if token == "" {
+			continue
+		}
 
-		if tokenOk && pagesOk && leadsOk {
-			return token, int(pagesFetched), int(leadsExtracted), nil
+		err = store.SaveQueryProgress(repository.QueryProgress{
+			Query:          query,
+			Location:       location,
+			Radius:         radius,
+			PagesFetched:   int(pagesFetched),
+			LeadsExtracted: int(leadsExtracted),
+			NextPageToken:  token,
+		})
+		if err != nil {
+			return fmt.Errorf("erro ao migrar token da consulta %s: %v", queryKey, err)
 		}
-		return "", 0, 0, fmt.Errorf("erro ao converter valores: %v", queryData)
+		migrated++
 	}
 
-	// Retorna valores padrão se a chave não existir
-	return "", 0, 0, nil
-}
+	err = os.Rename(filePath, filePath+".migrated")
+	if err != nil {
+		return fmt.Errorf("erro ao renomear o arquivo %s: %v", filePath, err)
+	}
 
-func saveToken(queryKey string, token string, pagesFetched int, leadsExtracted int) error {
-	var tokenStore TokenStore
+	log.Printf("%d tokens migrados de %s para o banco de dados", migrated, filePath)
+	return nil
+}
 
-	file, err := os.ReadFile("next_page_tokens.json")
-	if err == nil {
-		err = json.Unmarshal(file, &tokenStore)
-		if err != nil {
-			return fmt.Errorf("erro ao fazer parse do arquivo JSON: %v", err)
-		}
-	} else {
-		tokenStore = TokenStore{QueryTokens: make(map[string]map[string]interface{})}
+func parseQueryKey(queryKey string) (string, string, int, bool) {
+	radiusSep := strings.LastIndex(queryKey, "|")
+	if radiusSep < 0 {
+		return "", "", 0, false
+	}
+	radius, err := strconv.Atoi(queryKey[radiusSep+1:])
+	if err != nil {
+		return "", "", 0, false
 	}
 
-	if tokenStore.QueryTokens == nil {
-		log.Println("Mapa QueryTokens não está inicializado. Inicializando agora.")
-		tokenStore.QueryTokens = make(map[string]map[string]interface{})
+	locationSep := strings.LastIndex(queryKey[:radiusSep], "|")
+	if locationSep < 0 {
+		return "", "", 0, false
 	}
 
-	tokenStore.QueryTokens[queryKey] = map[string]interface{}{
-		"next_page_token": token,
-		"pages_fetched":   pagesFetched,
-		"leads_extracted": leadsExtracted,
+	return queryKey[:locationSep], queryKey[locationSep+1 : radiusSep], radius, true
+}
+
+func (s *Service) loadProgress(query string, location string, radius int) (string, int, int) {
+	if s.ProgressStore == nil {
+		return "", 0, 0
 	}
 
-	tokenStoreBytes, err := json.MarshalIndent(tokenStore, "", "  ")
+	progress, err := s.ProgressStore.LoadQueryProgress(query, location, radius)
 	if err != nil {
-		return fmt.Errorf("erro ao fazer marshal dos tokens: %v", err)
+		log.Printf("Erro ao carregar next_page_token da consulta %s: %v", generateQueryKey(query, location, radius), err)
+		return "", 0, 0
+	}
+	if progress == nil {
+		return "", 0, 0
 	}
+	return progress.NextPageToken, progress.PagesFetched, progress.LeadsExtracted
+}
 
-	err = os.WriteFile("/app/lead-search/next_page_tokens.json", tokenStoreBytes, 0644)
-	if err != nil {
-		return fmt.Errorf("erro ao salvar o arquivo JSON: %v", err)
+func (s *Service) saveProgress(query string, location string, radius int, token string, pagesFetched int, leadsExtracted int) {
+	if s.ProgressStore == nil {
+		return
 	}
 
-	log.Println("next_page_token salvo com sucesso")
-	return nil
+	err := s.ProgressStore.SaveQueryProgress(repository.QueryProgress{
+		Query:          query,
+		Location:       location,
+		Radius:         radius,
+		PagesFetched:   pagesFetched,
+		LeadsExtracted: leadsExtracted,
+		NextPageToken:  token,
+	})
+	if err != nil {
+		log.Printf("Erro ao salvar next_page_token da consulta %s: %v", generateQueryKey(query, location, radius), err)
+	}
 }
 
-func updateSearchProgress(db *sql.DB, progressID int, pagesFetched int, leadsExtracted int, searchDone bool) error {
-	searchDoneValue := 0
-	if searchDone {
-		searchDoneValue = 1
+func (s *Service) clearProgress(query string, location string, radius int) {
+	if s.ProgressStore == nil {
+		return
 	}
 
-	query := `
-        UPDATE search_progress 
-        SET pages_fetched = ?, leads_extracted = ?, search_done = ? 
-        WHERE id = ?`
-
-	_, err := db.Exec(query, pagesFetched, leadsExtracted, searchDoneValue, progressID)
+	err := s.ProgressStore.ClearQueryProgress(query, location, radius)
 	if err != nil {
-		return fmt.Errorf("Failed to update search progress: %v", err)
+		log.Printf("Erro ao limpar o progresso da consulta %s: %v", generateQueryKey(query, location, radius), err)
 	}
-
-	log.Printf("Search progress updated: %d pages, %d leads extracted, done: %v", pagesFetched, leadsExtracted, searchDone)
-	return nil
 }
 
-func (s *Service) SearchPlaces(query string, location string, radius int, maxPages int) ([]map[string]interface{}, error) {
-	client := resty.New()
-	url := "https://maps.googleapis.com/maps/api/place/textsearch/json"
+// A Text Search devolve no máximo 3 páginas de 20 resultados por consulta
+const (
+	MaxTextSearchPages   = 3
+	nextPageTokenDelay   = 2 * time.Second
+	nextPageTokenRetries = 5
+)
+
+// Chamado a cada página obtida com o número da página na consulta (a partir
+// de 1, contando as páginas lidas antes de uma retomada) e a quantidade de
+// resultados que ela trouxe
+type PageHandler func(page int, results int)
+
+// Busca as páginas da consulta. Com o ctx cancelado a busca para antes da
+// próxima página; o token da última página lida fica salvo no ProgressStore
+// para a consulta ser retomada. Qualquer erro vem junto com os lugares já
+// lidos.
+func (s *Service) SearchPlaces(ctx context.Context, query string, location string, radius int, maxPages int, maxResults int, onPage PageHandler) ([]map[string]interface{}, error) {
+	if maxPages <= 0 || maxPages > MaxTextSearchPages {
+		maxPages = MaxTextSearchPages
+	}
 
 	var allPlaces []map[string]interface{}
 	queryKey := generateQueryKey(query, location, radius)
 
-	pageToken, pagesFetched, leadsExtracted, err := loadToken(queryKey)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao carregar next_page_token: %v", err)
+	pageToken, pagesFetched, leadsExtracted := s.loadProgress(query, location, radius)
+	resumed := pageToken != ""
+	if resumed {
+		log.Printf("Retomando a consulta %s a partir da página %d", queryKey, pagesFetched+1)
 	}
 
+	page := 0
+	tokenRetries := 0
 	for {
-		params := map[string]string{
-			"query":    query,
-			"location": location,
-			"radius":   fmt.Sprintf("%d", radius),
-			"key":      s.APIKey,
-		}
-		if pageToken != "" {
-			params["pagetoken"] = pageToken
+		if err := ctx.Err(); err != nil {
+			return allPlaces, err
 		}
 
-		resp, err := client.R().
-			SetQueryParams(params).
-			Get(url)
-
+		result, err := s.Provider.Search(ctx, SearchRequest{
+			Query:     query,
+			Location:  location,
+			Radius:    radius,
+			PageToken: pageToken,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("error connecting to Google Places API: %v", err)
-		}
-
-		if resp.IsSuccess() {
-			var result struct {
-				Results       []PlaceResult `json:"results"`
-				Status        string        `json:"status"`
-				ErrorMessage  string        `json:"error_message"`
-				NextPageToken string        `json:"next_page_token"`
+			if ctx.Err() != nil {
+				return allPlaces, ctx.Err()
 			}
+			return allPlaces, err
+		}
 
-			err := json.Unmarshal(resp.Body(), &result)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing response: %v", err)
-			}
+		// O token salvo vem de uma execução anterior e não está mais esperando
+		// ficar válido: INVALID_REQUEST é token expirado e a consulta recomeça
+		// da primeira página, sem as novas tentativas
+		if result.Status == StatusInvalidRequest && resumed && page == 0 {
+			log.Printf("next_page_token salvo para %s não é mais válido, recomeçando a consulta", queryKey)
+			s.clearProgress(query, location, radius)
+			resumed = false
+			pageToken, pagesFetched, leadsExtracted = "", 0, 0
+			continue
+		}
 
-			if result.Status == "ZERO_RESULTS" {
-				log.Printf("Nenhum resultado encontrado para a consulta: %s", query)
-				break
-			} else if result.Status != "OK" {
-				return nil, fmt.Errorf("API error: %s, message: %s", result.Status, result.ErrorMessage)
+		// O next_page_token demora alguns segundos para ficar válido; até lá a
+		// API responde INVALID_REQUEST para a mesma requisição
+		if result.Status == StatusInvalidRequest && pageToken != "" && tokenRetries < nextPageTokenRetries {
+			tokenRetries++
+			log.Printf("next_page_token ainda não disponível, tentando novamente (%d/%d)", tokenRetries, nextPageTokenRetries)
+			if err := sleepContext(ctx, s.PageTokenDelay); err != nil {
+				return allPlaces, err
 			}
+			continue
+		}
+		tokenRetries = 0
 
-			for _, place := range result.Results {
-				placeDetails := map[string]interface{}{
-					"Name":              place.Name,
-					"FormattedAddress":  place.FormattedAddress,
-					"PlaceID":           place.PlaceID,
-					"Rating":            place.Rating,
-					"UserRatingsTotal":  place.UserRatingsTotal,
-					"PriceLevel":        place.PriceLevel,
-					"BusinessStatus":    place.BusinessStatus,
-					"Vicinity":          place.Vicinity,
-					"PermanentlyClosed": place.PermanentlyClosed,
-					"Types":             place.Types,
-				}
-				allPlaces = append(allPlaces, placeDetails)
-				leadsExtracted++
-
-				if len(allPlaces) >= 1 {
-					log.Println("Apenas uma empresa foi obtida. Interrompendo a busca.")
-					break
-				}
-			}
+		if result.Status == StatusZeroResults {
+			log.Printf("Nenhum resultado encontrado para a consulta: %s", query)
+			break
+		} else if result.Status != StatusOK {
+			return allPlaces, newStatusError("search", result.Status, result.ErrorMessage)
+		}
 
-			if len(allPlaces) >= 1 || result.NextPageToken == "" || pagesFetched >= maxPages {
+		for _, place := range result.Results {
+			if maxResults > 0 && len(allPlaces) >= maxResults {
 				break
 			}
 
-			pagesFetched++
-			log.Printf("Página %d obtida, total de resultados até agora: %d", pagesFetched, leadsExtracted)
-
-			saveToken(queryKey, result.NextPageToken, pagesFetched, leadsExtracted)
-
-			if result.NextPageToken == "" || pagesFetched >= maxPages {
-				break
+			placeDetails := map[string]interface{}{
+				"Name":              place.Name,
+				"FormattedAddress":  place.FormattedAddress,
+				"PlaceID":           place.PlaceID,
+				"Rating":            place.Rating,
+				"UserRatingsTotal":  place.UserRatingsTotal,
+				"PriceLevel":        place.PriceLevel,
+				"BusinessStatus":    place.BusinessStatus,
+				"Vicinity":          place.Vicinity,
+				"PermanentlyClosed": place.PermanentlyClosed,
+				"Types":             place.Types,
 			}
-
-			pageToken = result.NextPageToken
-			time.Sleep(2 * time.Second)
-		} else {
-			return nil, fmt.Errorf("failed to get data: %v", resp.Status())
-		}
-	}
-
-	log.Printf("Total de resultados obtidos: %d", leadsExtracted)
-	return allPlaces, nil
-}
-
-func SaveProgressToDB(db *sql.DB, query string, location string, radius int, pagesFetched int, leadsExtracted int, token string) error {
-	log.Println("saveProgressToDB: Entrando na função")
-	log.Printf("saveProgressToDB: query='%s', location='%s', radius=%d, pagesFetched=%d, leadsExtracted=%d, token='%s'\n",
-		query, location, radius, pagesFetched, leadsExtracted, token)
-
-	// Verifica se já existe um registro para a consulta
-	var exists bool
-	queryStr := `SELECT EXISTS(SELECT 1 FROM query_progress WHERE query=? AND location=? AND radius=?)`
-	err := db.QueryRow(queryStr, query, location, radius).Scan(&exists)
-	if err != nil {
-		log.Printf("Erro ao verificar a existência do registro: %v\n", err)
-		return err
-	}
-
-	if exists {
-		// Atualiza o progresso existente
-		log.Println("saveProgressToDB: Atualizando registro existente.")
-		updateStr := `UPDATE query_progress SET pages_fetched=?, leads_extracted=?, next_page_token=? WHERE query=? AND location=? AND radius=?`
-		result, err := db.Exec(updateStr, pagesFetched, leadsExtracted, token, query, location, radius)
-		if err != nil {
-			log.Printf("Erro ao atualizar o registro: %v\n", err)
-			return err
-		}
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			log.Printf("Erro ao obter linhas afetadas na atualização: %v\n", err)
-			return err
+			allPlaces = append(allPlaces, placeDetails)
+			leadsExtracted++
 		}
-		if rowsAffected == 0 {
-			log.Printf("Aviso: Nenhuma linha foi atualizada para query='%s', location='%s', radius=%d\n", query, location, radius)
-		} else {
-			log.Printf("Sucesso: %d linha(s) foram atualizadas para query='%s', location='%s', radius=%d\n", rowsAffected, query, location, radius)
-		}
-	} else {
-		// Insere um novo progresso
-		log.Println("saveProgressToDB: Inserindo novo registro.")
-		insertStr := `INSERT INTO query_progress (query, location, radius, pages_fetched, leads_extracted, next_page_token) VALUES (?, ?, ?, ?, ?, ?)`
-		result, err := db.Exec(insertStr, query, location, radius, pagesFetched, leadsExtracted, token)
-		if err != nil {
-			log.Printf("Erro ao inserir o registro: %v\n", err)
-			return err
-		}
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			log.Printf("Erro ao obter linhas afetadas na inserção: %v\n", err)
-			return err
-		}
-		if rowsAffected == 0 {
-			log.Printf("Aviso: Nenhuma linha foi inserida para query='%s', location='%s', radius=%d\n", query, location, radius)
-		} else {
-			log.Printf("Sucesso: %d linha(s) foram inseridas para query='%s', location='%s', radius=%d\n", rowsAffected, query, location, radius)
-		}
-	}
-
-	return nil
-}
-
-func (s *Service) GetPlaceDetails(placeID string) (map[string]interface{}, error) {
-	client := resty.New()
 
-	url := "https://maps.googleapis.com/maps/api/place/details/json"
-	resp, err := client.R().
-		SetQueryParams(map[string]string{
-			"place_id": placeID,
-			"key":      s.APIKey,
-			"fields":   "name,formatted_address,international_phone_number,website,rating,address_components,editorial_summary",
-		}).
-		Get(url)
-
-	if err != nil {
-		return nil, fmt.Errorf("error connecting to Google Places Details API: %v", err)
-	}
-
-	if resp.IsSuccess() {
-		var result struct {
-			Result struct {
-				Name                     string  `json:"name"`
-				FormattedAddress         string  `json:"formatted_address"`
-				InternationalPhoneNumber string  `json:"international_phone_number"`
-				Website                  string  `json:"website"`
-				Rating                   float64 `json:"rating"`
-				AddressComponents        []struct {
-					LongName  string   `json:"long_name"`
-					ShortName string   `json:"short_name"`
-					Types     []string `json:"types"`
-				} `json:"address_components"`
-				EditorialSummary struct {
-					Overview string `json:"overview"`
-				} `json:"editorial_summary"`
-			} `json:"result"`
-			Status       string `json:"status"`
-			ErrorMessage string `json:"error_message"`
+		page++
+		pagesFetched++
+		log.Printf("Página %d obtida com %d resultados, total de resultados até agora: %d", pagesFetched, len(result.Results), len(allPlaces))
+		if onPage != nil {
+			onPage(pagesFetched, len(result.Results))
 		}
 
-		err := json.Unmarshal(resp.Body(), &result)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing place details response: %v", err)
+		if result.NextPageToken == "" {
+			break
 		}
 
-		if result.Status != "OK" {
-			return nil, fmt.Errorf("error from API: %s, message: %s", result.Status, result.ErrorMessage)
+		// Limite atingido encerra a consulta: a próxima busca com a mesma chave
+		// começa da primeira página
+		if pagesFetched >= maxPages || (maxResults > 0 && len(allPlaces) >= maxResults) {
+			log.Printf("Limite atingido (%d páginas, %d resultados)", maxPages, maxResults)
+			break
 		}
 
-		var city, state, zipCode, country, route, neighborhood, streetNumber string
-		for _, component := range result.Result.AddressComponents {
-			for _, ctype := range component.Types {
-				switch ctype {
-				case "locality":
-					city = component.LongName
-				case "administrative_area_level_1":
-					state = component.ShortName
-				case "postal_code":
-					zipCode = component.LongName
-				case "country":
-					country = component.LongName
-				case "street_number":
-					streetNumber = component.LongName
-				case "route":
-					route = component.LongName
-				case "neighborhood", "sublocality", "sublocality_level_1", "sublocality_level_2", "administrative_area_level_2":
-					if neighborhood == "" {
-						neighborhood = component.LongName
-					}
-
-				}
-			}
-		}
+		// Guarda o token para que uma busca cancelada ou interrompida continue daqui
+		s.saveProgress(query, location, radius, result.NextPageToken, pagesFetched, leadsExtracted)
 
-		addressParts := []string{}
-		if route != "" {
-			addressParts = append(addressParts, route)
+		pageToken = result.NextPageToken
+		if err := sleepContext(ctx, s.PageTokenDelay); err != nil {
+			return allPlaces, err
 		}
-		if streetNumber != "" {
-			addressParts = append(addressParts, streetNumber)
-		}
-		if neighborhood != "" {
-			addressParts = append(addressParts, neighborhood)
-		}
-		address := strings.Join(addressParts, ", ")
+	}
 
-		var description string
-		if result.Result.EditorialSummary.Overview != "" {
-			description = fmt.Sprintf("(Google Places: %s)", result.Result.EditorialSummary.Overview)
-		} else {
-			description = "(Google Places: No description available)"
-		}
+	// Consulta encerrada: o token salvo não deve ser reaproveitado
+	s.clearProgress(query, location, radius)
 
-		log.Printf("Address components included: %v", addressParts)
-
-		return map[string]interface{}{
-			"Name":                     result.Result.Name,
-			"FormattedAddress":         address,
-			"InternationalPhoneNumber": result.Result.InternationalPhoneNumber,
-			"Website":                  result.Result.Website,
-			"Rating":                   result.Result.Rating,
-			"City":                     city,
-			"State":                    state,
-			"ZIPCode":                  zipCode,
-			"Country":                  country,
-			"PlaceID":                  placeID,
-			"Description":              description,
-		}, nil
-	}
+	log.Printf("Total de resultados obtidos: %d", len(allPlaces))
+	return allPlaces, nil
+}
 
-	return nil, fmt.Errorf("failed to get place details: %v", resp.Status())
+func (s *Service) GetPlaceDetails(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	return s.Provider.Details(ctx, placeID)
 }
@@ -1,8 +1,12 @@
-
 package googleplaces
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+
+	"lead-search/repository"
 )
 
 func TestNewService(t *testing.T) {
@@ -12,3 +16,142 @@ func TestNewService(t *testing.T) {
         t.Errorf("expected APIKey %v, got %v", apiKey, service.APIKey)
     }
 }
+
+// Provider com páginas infinitas: cada página aponta para a seguinte. Com
+// failAt a requisição de número failAt falha e o token expired é recusado
+type pagedProvider struct {
+	FixtureProvider
+	tokens  []string
+	failAt  int
+	expired string
+}
+
+func (p *pagedProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	p.tokens = append(p.tokens, req.PageToken)
+	page := len(p.tokens)
+	if page == p.failAt {
+		return SearchPage{}, errors.New("connection reset")
+	}
+	if p.expired != "" && req.PageToken == p.expired {
+		return SearchPage{Status: StatusInvalidRequest}, nil
+	}
+	return SearchPage{
+		Status:        StatusOK,
+		Results:       []PlaceResult{{PlaceID: fmt.Sprintf("place_%d", page)}},
+		NextPageToken: fmt.Sprintf("token_%d", page),
+	}, nil
+}
+
+type memoryProgressStore struct {
+	progress map[string]repository.QueryProgress
+}
+
+func (s *memoryProgressStore) LoadQueryProgress(query string, location string, radius int) (*repository.QueryProgress, error) {
+	progress, ok := s.progress[generateQueryKey(query, location, radius)]
+	if !ok {
+		return nil, nil
+	}
+	return &progress, nil
+}
+
+func (s *memoryProgressStore) SaveQueryProgress(progress repository.QueryProgress) error {
+	s.progress[generateQueryKey(progress.Query, progress.Location, progress.Radius)] = progress
+	return nil
+}
+
+func (s *memoryProgressStore) ClearQueryProgress(query string, location string, radius int) error {
+	delete(s.progress, generateQueryKey(query, location, radius))
+	return nil
+}
+
+// Uma busca que para no limite de páginas não deixa token para a próxima
+// busca com a mesma chave
+func TestSearchPlacesLimitClearsProgress(t *testing.T) {
+	provider := &pagedProvider{}
+	store := &memoryProgressStore{progress: make(map[string]repository.QueryProgress)}
+	service := NewServiceWithProvider(provider)
+	service.PageTokenDelay = 0
+	service.ProgressStore = store
+
+	for i := 0; i < 2; i++ {
+		places, err := service.SearchPlaces(context.Background(), "padaria", "-23.5,-46.6", 500, 2, 0, nil)
+		if err != nil {
+			t.Fatalf("SearchPlaces: %v", err)
+		}
+		if len(places) != 2 {
+			t.Fatalf("expected 2 places, got %d", len(places))
+		}
+		if len(store.progress) != 0 {
+			t.Fatalf("expected no saved progress after search %d, got %v", i+1, store.progress)
+		}
+	}
+
+	// As duas buscas começam da primeira página
+	expected := []string{"", "token_1", "", "token_3"}
+	if fmt.Sprint(provider.tokens) != fmt.Sprint(expected) {
+		t.Errorf("expected page tokens %q, got %q", expected, provider.tokens)
+	}
+}
+
+// Um erro numa página devolve os lugares das páginas anteriores
+func TestSearchPlacesErrorKeepsFetchedPlaces(t *testing.T) {
+	provider := &pagedProvider{failAt: 2}
+	service := NewServiceWithProvider(provider)
+	service.PageTokenDelay = 0
+	service.ProgressStore = &memoryProgressStore{progress: make(map[string]repository.QueryProgress)}
+
+	places, err := service.SearchPlaces(context.Background(), "padaria", "-23.5,-46.6", 500, 3, 0, nil)
+	if err == nil {
+		t.Fatal("expected an error from the second page")
+	}
+	if len(places) != 1 || places[0]["PlaceID"] != "place_1" {
+		t.Errorf("expected the first page places, got %v", places)
+	}
+}
+
+// Uma consulta retomada numera as páginas a partir das já lidas
+func TestSearchPlacesResumeKeepsPageNumbers(t *testing.T) {
+	provider := &pagedProvider{}
+	store := &memoryProgressStore{progress: make(map[string]repository.QueryProgress)}
+	store.progress[generateQueryKey("padaria", "-23.5,-46.6", 500)] = repository.QueryProgress{
+		Query: "padaria", Location: "-23.5,-46.6", Radius: 500, NextPageToken: "saved", PagesFetched: 1, LeadsExtracted: 1,
+	}
+	service := NewServiceWithProvider(provider)
+	service.PageTokenDelay = 0
+	service.ProgressStore = store
+
+	var pages []int
+	_, err := service.SearchPlaces(context.Background(), "padaria", "-23.5,-46.6", 500, 3, 0, func(page int, results int) {
+		pages = append(pages, page)
+	})
+	if err != nil {
+		t.Fatalf("SearchPlaces: %v", err)
+	}
+	if fmt.Sprint(pages) != "[2 3]" {
+		t.Errorf("expected pages [2 3], got %v", pages)
+	}
+}
+
+// Um token salvo expirado recomeça a consulta sem esperar pelo token
+func TestSearchPlacesExpiredTokenRestarts(t *testing.T) {
+	provider := &pagedProvider{expired: "saved"}
+	store := &memoryProgressStore{progress: make(map[string]repository.QueryProgress)}
+	store.progress[generateQueryKey("padaria", "-23.5,-46.6", 500)] = repository.QueryProgress{
+		Query: "padaria", Location: "-23.5,-46.6", Radius: 500, NextPageToken: "saved", PagesFetched: 1, LeadsExtracted: 1,
+	}
+	service := NewServiceWithProvider(provider)
+	service.PageTokenDelay = 0
+	service.ProgressStore = store
+
+	places, err := service.SearchPlaces(context.Background(), "padaria", "-23.5,-46.6", 500, 1, 0, nil)
+	if err != nil {
+		t.Fatalf("SearchPlaces: %v", err)
+	}
+	if len(places) != 1 {
+		t.Errorf("expected 1 place, got %d", len(places))
+	}
+	expected := []string{"saved", ""}
+	if fmt.Sprint(provider.tokens) != fmt.Sprint(expected) {
+		t.Errorf("expected page tokens %q, got %q", expected, provider.tokens)
+	}
+}
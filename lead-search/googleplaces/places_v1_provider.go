@@ -0,0 +1,250 @@
+package googleplaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const DefaultPlacesV1BaseURL = "https://places.googleapis.com/v1"
+
+// Campos pedidos à Places API (New). Cada campo entra na cobrança, então as
+// máscaras trazem só o que o db.Lead grava.
+const (
+	placesV1SearchFieldMask = "places.id,places.displayName,places.formattedAddress,places.shortFormattedAddress," +
+		"places.rating,places.userRatingCount,places.priceLevel,places.businessStatus,places.types,nextPageToken"
+	placesV1DetailsFieldMask = "id,displayName,addressComponents,internationalPhoneNumber,websiteUri," +
+		"regularOpeningHours.weekdayDescriptions,rating,userRatingCount,priceLevel,businessStatus,types,editorialSummary"
+)
+
+// A Text Search (New) aceita no máximo 20 resultados por página
+const placesV1PageSize = 20
+
+// Provider da Places API (New). A API nova não tem geocodificação, então
+// Geocode continua usando a Geocoding API pelo Geocoder.
+type PlacesV1Provider struct {
+	APIKey   string
+	BaseURL  string
+	Client   *resty.Client
+	Geocoder *GoogleProvider
+}
+
+func NewPlacesV1Provider(apiKey string, baseURL string, client *resty.Client, geocoder *GoogleProvider) *PlacesV1Provider {
+	if baseURL == "" {
+		baseURL = DefaultPlacesV1BaseURL
+	}
+	if client == nil {
+		client = NewHTTPClient()
+	}
+	if geocoder == nil {
+		geocoder = NewGoogleProvider(apiKey, "", client)
+	}
+	return &PlacesV1Provider{
+		APIKey:   apiKey,
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Client:   client,
+		Geocoder: geocoder,
+	}
+}
+
+func (p *PlacesV1Provider) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	return p.Geocoder.Geocode(ctx, address)
+}
+
+type placesV1Place struct {
+	ID          string `json:"id"`
+	DisplayName struct {
+		Text string `json:"text"`
+	} `json:"displayName"`
+	FormattedAddress      string   `json:"formattedAddress"`
+	ShortFormattedAddress string   `json:"shortFormattedAddress"`
+	Rating                float64  `json:"rating"`
+	UserRatingCount       int      `json:"userRatingCount"`
+	PriceLevel            string   `json:"priceLevel"`
+	BusinessStatus        string   `json:"businessStatus"`
+	Types                 []string `json:"types"`
+	InternationalPhone    string   `json:"internationalPhoneNumber"`
+	WebsiteURI            string   `json:"websiteUri"`
+	AddressComponents     []struct {
+		LongText  string   `json:"longText"`
+		ShortText string   `json:"shortText"`
+		Types     []string `json:"types"`
+	} `json:"addressComponents"`
+	RegularOpeningHours struct {
+		WeekdayDescriptions []string `json:"weekdayDescriptions"`
+	} `json:"regularOpeningHours"`
+	EditorialSummary struct {
+		Text string `json:"text"`
+	} `json:"editorialSummary"`
+}
+
+type placesV1Error struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (p *PlacesV1Provider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	body := map[string]interface{}{
+		"textQuery": req.Query,
+		"pageSize":  placesV1PageSize,
+	}
+	if center, ok := parseLatLng(req.Location); ok {
+		body["locationBias"] = map[string]interface{}{
+			"circle": map[string]interface{}{
+				"center": map[string]float64{"latitude": center.Lat, "longitude": center.Lng},
+				"radius": float64(req.Radius),
+			},
+		}
+	}
+	if req.PageToken != "" {
+		body["pageToken"] = req.PageToken
+	}
+
+	resp, err := p.Client.R().
+		SetContext(ctx).
+		SetHeader("X-Goog-Api-Key", p.APIKey).
+		SetHeader("X-Goog-FieldMask", placesV1SearchFieldMask).
+		SetBody(body).
+		Post(p.BaseURL + "/places:searchText")
+	if err != nil {
+		return SearchPage{}, newTransportError("search", err)
+	}
+
+	if !resp.IsSuccess() {
+		apiErr := parsePlacesV1Error(resp)
+		// Token de página expirado: o Service recomeça a consulta do início
+		if req.PageToken != "" && apiErr.Error.Status == "INVALID_ARGUMENT" {
+			return SearchPage{Status: StatusInvalidRequest, ErrorMessage: apiErr.Error.Message}, nil
+		}
+		return SearchPage{}, newHTTPError("search", resp.StatusCode(), apiErr.Error.Status, apiErr.Error.Message)
+	}
+
+	var result struct {
+		Places        []placesV1Place `json:"places"`
+		NextPageToken string          `json:"nextPageToken"`
+	}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return SearchPage{}, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	page := SearchPage{Status: StatusOK, NextPageToken: result.NextPageToken}
+	if len(result.Places) == 0 {
+		page.Status = StatusZeroResults
+	}
+	for _, place := range result.Places {
+		page.Results = append(page.Results, PlaceResult{
+			Name:              place.DisplayName.Text,
+			FormattedAddress:  place.FormattedAddress,
+			PlaceID:           place.ID,
+			Rating:            place.Rating,
+			UserRatingsTotal:  place.UserRatingCount,
+			PriceLevel:        placesV1PriceLevel(place.PriceLevel),
+			BusinessStatus:    place.BusinessStatus,
+			Vicinity:          place.ShortFormattedAddress,
+			PermanentlyClosed: place.BusinessStatus == "CLOSED_PERMANENTLY",
+			Types:             place.Types,
+		})
+	}
+	return page, nil
+}
+
+func (p *PlacesV1Provider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	resp, err := p.Client.R().
+		SetContext(ctx).
+		SetHeader("X-Goog-Api-Key", p.APIKey).
+		SetHeader("X-Goog-FieldMask", placesV1DetailsFieldMask).
+		SetQueryParam("languageCode", "pt-BR").
+		Get(p.BaseURL + "/places/" + url.PathEscape(placeID))
+	if err != nil {
+		return nil, newTransportError("details", err)
+	}
+
+	if !resp.IsSuccess() {
+		apiErr := parsePlacesV1Error(resp)
+		return nil, newHTTPError("details", resp.StatusCode(), apiErr.Error.Status, apiErr.Error.Message)
+	}
+
+	var place placesV1Place
+	err = json.Unmarshal(resp.Body(), &place)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing place details response: %v", err)
+	}
+
+	components := make([]addressComponent, 0, len(place.AddressComponents))
+	for _, component := range place.AddressComponents {
+		components = append(components, addressComponent{
+			LongName:  component.LongText,
+			ShortName: component.ShortText,
+			Types:     component.Types,
+		})
+	}
+	address := parseAddressComponents(components)
+
+	return map[string]interface{}{
+		"Name":                     place.DisplayName.Text,
+		"FormattedAddress":         address.Street,
+		"InternationalPhoneNumber": place.InternationalPhone,
+		"Website":                  place.WebsiteURI,
+		"Rating":                   place.Rating,
+		"UserRatingsTotal":         place.UserRatingCount,
+		"PriceLevel":               placesV1PriceLevel(place.PriceLevel),
+		"BusinessStatus":           place.BusinessStatus,
+		"PermanentlyClosed":        place.BusinessStatus == "CLOSED_PERMANENTLY",
+		"Types":                    place.Types,
+		"OpeningHours":             place.RegularOpeningHours.WeekdayDescriptions,
+		"City":                     address.City,
+		"State":                    address.State,
+		"ZIPCode":                  address.ZIPCode,
+		"Country":                  address.Country,
+		"PlaceID":                  placeID,
+		"Description":              editorialDescription(place.EditorialSummary.Text),
+	}, nil
+}
+
+func parsePlacesV1Error(resp *resty.Response) placesV1Error {
+	var apiErr placesV1Error
+	if err := json.Unmarshal(resp.Body(), &apiErr); err != nil || apiErr.Error.Message == "" {
+		apiErr.Error.Message = resp.Status()
+	}
+	return apiErr
+}
+
+// A API nova devolve o nível de preço como enum; o db.Lead guarda o número da API legada
+func placesV1PriceLevel(level string) int {
+	switch level {
+	case "PRICE_LEVEL_INEXPENSIVE":
+		return 1
+	case "PRICE_LEVEL_MODERATE":
+		return 2
+	case "PRICE_LEVEL_EXPENSIVE":
+		return 3
+	case "PRICE_LEVEL_VERY_EXPENSIVE":
+		return 4
+	}
+	return 0
+}
+
+func parseLatLng(location string) (LatLng, bool) {
+	parts := strings.Split(location, ",")
+	if len(parts) != 2 {
+		return LatLng{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return LatLng{}, false
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return LatLng{}, false
+	}
+	return LatLng{Lat: lat, Lng: lng}, true
+}
@@ -0,0 +1,65 @@
+package googleplaces
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlacesV1ProviderSearchAndDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "test-key" || r.Header.Get("X-Goog-FieldMask") == "" {
+			t.Errorf("missing API key or field mask on %s", r.URL)
+		}
+
+		switch r.URL.Path {
+		case "/places:searchText":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["textQuery"] != "padaria" || body["locationBias"] == nil {
+				t.Errorf("unexpected search body: %v", body)
+			}
+			w.Write([]byte(`{"places":[{"id":"abc","displayName":{"text":"Padaria"},"priceLevel":"PRICE_LEVEL_MODERATE",
+				"businessStatus":"OPERATIONAL","userRatingCount":10}],"nextPageToken":"next"}`))
+		case "/places/abc":
+			w.Write([]byte(`{"id":"abc","displayName":{"text":"Padaria"},"internationalPhoneNumber":"+55 11 3061-0001",
+				"websiteUri":"https://padaria.example.com","regularOpeningHours":{"weekdayDescriptions":["segunda-feira: 06:00–22:00"]},
+				"addressComponents":[{"longText":"São Paulo","shortText":"São Paulo","types":["locality"]},
+				{"longText":"São Paulo","shortText":"SP","types":["administrative_area_level_1"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"not found","status":"NOT_FOUND"}}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewPlacesV1Provider("test-key", server.URL, nil, nil)
+
+	page, err := provider.Search(context.Background(), SearchRequest{Query: "padaria", Location: "-23.5,-46.6", Radius: 500})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if page.Status != StatusOK || page.NextPageToken != "next" || len(page.Results) != 1 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if page.Results[0].PriceLevel != 2 || page.Results[0].UserRatingsTotal != 10 {
+		t.Errorf("unexpected place: %+v", page.Results[0])
+	}
+
+	details, err := provider.Details(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("Details: %v", err)
+	}
+	if details["Website"] != "https://padaria.example.com" || details["State"] != "SP" {
+		t.Errorf("unexpected details: %+v", details)
+	}
+	if hours, _ := details["OpeningHours"].([]string); len(hours) != 1 {
+		t.Errorf("expected opening hours, got %v", details["OpeningHours"])
+	}
+
+	if _, err := provider.Details(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown place")
+	}
+}
@@ -0,0 +1,243 @@
+package googleplaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Fonte dos dados de lugares. O Service cuida da paginação, do cache e da
+// varredura; o provider só faz uma chamada por vez à API (ou a um substituto).
+// Cancelar o ctx interrompe a chamada em andamento.
+type PlacesProvider interface {
+	Geocode(ctx context.Context, address string) (GeocodeResult, error)
+	Search(ctx context.Context, req SearchRequest) (SearchPage, error)
+	Details(ctx context.Context, placeID string) (map[string]interface{}, error)
+}
+
+// Status devolvidos pela Places API e interpretados pelo Service
+const (
+	StatusOK             = "OK"
+	StatusZeroResults    = "ZERO_RESULTS"
+	StatusInvalidRequest = "INVALID_REQUEST"
+)
+
+type GeocodeResult struct {
+	Location         LatLng
+	Bounds           Bounds // bounds da área, ou o viewport quando a API não devolve bounds
+	FormattedAddress string
+	Source           string // gravado em geocode_cache.source
+}
+
+type SearchRequest struct {
+	Query     string
+	Location  string
+	Radius    int
+	PageToken string
+}
+
+// Uma página da Text Search. Status diferente de OK não é erro do provider:
+// o Service decide se tenta de novo, recomeça ou desiste.
+type SearchPage struct {
+	Status        string
+	ErrorMessage  string
+	Results       []PlaceResult
+	NextPageToken string
+}
+
+func parseGeocodeResponse(address string, body []byte) (GeocodeResult, error) {
+	type point struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	}
+	type box struct {
+		NorthEast point `json:"northeast"`
+		SouthWest point `json:"southwest"`
+	}
+	var result struct {
+		Results []struct {
+			FormattedAddress string `json:"formatted_address"`
+			Geometry         struct {
+				Location point `json:"location"`
+				Bounds   *box  `json:"bounds"`
+				Viewport box   `json:"viewport"`
+			} `json:"geometry"`
+		} `json:"results"`
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+	}
+
+	err := json.Unmarshal(body, &result)
+	if err != nil {
+		return GeocodeResult{}, fmt.Errorf("error parsing geocode response: %v", err)
+	}
+
+	if result.Status != StatusOK {
+		return GeocodeResult{}, newStatusError("geocode", result.Status, result.ErrorMessage)
+	}
+
+	if len(result.Results) == 0 {
+		return GeocodeResult{}, newStatusError("geocode", StatusZeroResults, "no results found for address: "+address)
+	}
+
+	first := result.Results[0]
+
+	// bounds cobre a área inteira da cidade/bairro; viewport é só a janela sugerida
+	area := first.Geometry.Viewport
+	if first.Geometry.Bounds != nil {
+		area = *first.Geometry.Bounds
+	}
+
+	return GeocodeResult{
+		Location: LatLng{Lat: first.Geometry.Location.Lat, Lng: first.Geometry.Location.Lng},
+		Bounds: Bounds{
+			NorthEast: LatLng{Lat: area.NorthEast.Lat, Lng: area.NorthEast.Lng},
+			SouthWest: LatLng{Lat: area.SouthWest.Lat, Lng: area.SouthWest.Lng},
+		},
+		FormattedAddress: first.FormattedAddress,
+	}, nil
+}
+
+func parseTextSearchResponse(body []byte) (SearchPage, error) {
+	var result struct {
+		Results       []PlaceResult `json:"results"`
+		Status        string        `json:"status"`
+		ErrorMessage  string        `json:"error_message"`
+		NextPageToken string        `json:"next_page_token"`
+	}
+
+	err := json.Unmarshal(body, &result)
+	if err != nil {
+		return SearchPage{}, fmt.Errorf("error parsing response: %v", err)
+	}
+
+	return SearchPage{
+		Status:        result.Status,
+		ErrorMessage:  result.ErrorMessage,
+		Results:       result.Results,
+		NextPageToken: result.NextPageToken,
+	}, nil
+}
+
+func parseDetailsResponse(placeID string, body []byte) (map[string]interface{}, error) {
+	var result struct {
+		Result struct {
+			Name                     string  `json:"name"`
+			FormattedAddress         string  `json:"formatted_address"`
+			InternationalPhoneNumber string  `json:"international_phone_number"`
+			Website                  string  `json:"website"`
+			Rating                   float64 `json:"rating"`
+			AddressComponents        []struct {
+				LongName  string   `json:"long_name"`
+				ShortName string   `json:"short_name"`
+				Types     []string `json:"types"`
+			} `json:"address_components"`
+			EditorialSummary struct {
+				Overview string `json:"overview"`
+			} `json:"editorial_summary"`
+		} `json:"result"`
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+	}
+
+	err := json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing place details response: %v", err)
+	}
+
+	if result.Status != StatusOK {
+		return nil, newStatusError("details", result.Status, result.ErrorMessage)
+	}
+
+	components := make([]addressComponent, 0, len(result.Result.AddressComponents))
+	for _, component := range result.Result.AddressComponents {
+		components = append(components, addressComponent{
+			LongName:  component.LongName,
+			ShortName: component.ShortName,
+			Types:     component.Types,
+		})
+	}
+	address := parseAddressComponents(components)
+
+	description := editorialDescription(result.Result.EditorialSummary.Overview)
+
+	return map[string]interface{}{
+		"Name":                     result.Result.Name,
+		"FormattedAddress":         address.Street,
+		"InternationalPhoneNumber": result.Result.InternationalPhoneNumber,
+		"Website":                  result.Result.Website,
+		"Rating":                   result.Result.Rating,
+		"City":                     address.City,
+		"State":                    address.State,
+		"ZIPCode":                  address.ZIPCode,
+		"Country":                  address.Country,
+		"PlaceID":                  placeID,
+		"Description":              description,
+	}, nil
+}
+
+type addressComponent struct {
+	LongName  string
+	ShortName string
+	Types     []string
+}
+
+type placeAddress struct {
+	Street  string // logradouro, número e bairro
+	City    string
+	State   string
+	ZIPCode string
+	Country string
+}
+
+func parseAddressComponents(components []addressComponent) placeAddress {
+	var address placeAddress
+	var route, neighborhood, streetNumber string
+	for _, component := range components {
+		for _, ctype := range component.Types {
+			switch ctype {
+			case "locality":
+				address.City = component.LongName
+			case "administrative_area_level_1":
+				address.State = component.ShortName
+			case "postal_code":
+				address.ZIPCode = component.LongName
+			case "country":
+				address.Country = component.LongName
+			case "street_number":
+				streetNumber = component.LongName
+			case "route":
+				route = component.LongName
+			case "neighborhood", "sublocality", "sublocality_level_1", "sublocality_level_2", "administrative_area_level_2":
+				if neighborhood == "" {
+					neighborhood = component.LongName
+				}
+
+			}
+		}
+	}
+
+	addressParts := []string{}
+	if route != "" {
+		addressParts = append(addressParts, route)
+	}
+	if streetNumber != "" {
+		addressParts = append(addressParts, streetNumber)
+	}
+	if neighborhood != "" {
+		addressParts = append(addressParts, neighborhood)
+	}
+	address.Street = strings.Join(addressParts, ", ")
+
+	log.Printf("Address components included: %v", addressParts)
+	return address
+}
+
+func editorialDescription(overview string) string {
+	if overview != "" {
+		return fmt.Sprintf("(Google Places: %s)", overview)
+	}
+	return "(Google Places: No description available)"
+}
@@ -0,0 +1,185 @@
+package googleplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// SKUs cobrados separadamente pelo Google
+const (
+	SKUGeocoding    = "geocoding"
+	SKUTextSearch   = "text_search"
+	SKUPlaceDetails = "place_details"
+)
+
+// Preço estimado por chamada em USD, pela tabela pública do Google
+var DefaultSKUCosts = map[string]float64{
+	SKUGeocoding:    0.005,
+	SKUTextSearch:   0.032,
+	SKUPlaceDetails: 0.025,
+}
+
+var DefaultSKUQPS = map[string]float64{
+	SKUGeocoding:    10,
+	SKUTextSearch:   5,
+	SKUPlaceDetails: 10,
+}
+
+var ErrBudgetExceeded = errors.New("daily Google API budget exceeded")
+
+type LimiterConfig struct {
+	QPS           map[string]float64 // chamadas por segundo por SKU (0 = sem limite)
+	Costs         map[string]float64 // custo estimado por chamada em USD
+	DailyBudget   float64            // gasto máximo por dia (UTC) em USD, 0 = sem limite
+	BlockOnBudget bool               // espera o dia seguinte em vez de falhar quando o orçamento acaba
+}
+
+// Limitador compartilhado por todas as buscas: espaça as chamadas de cada SKU
+// e soma o gasto estimado do dia
+type Limiter struct {
+	config LimiterConfig
+
+	mu       sync.Mutex
+	nextCall map[string]time.Time
+	day      string
+	spent    float64
+}
+
+// spentToday é o gasto já registrado hoje (ex.: lido do ledger na inicialização)
+func NewLimiter(config LimiterConfig, spentToday float64) *Limiter {
+	if config.QPS == nil {
+		config.QPS = DefaultSKUQPS
+	}
+	if config.Costs == nil {
+		config.Costs = DefaultSKUCosts
+	}
+	return &Limiter{
+		config:   config,
+		nextCall: make(map[string]time.Time),
+		day:      currentDay(time.Now()),
+		spent:    spentToday,
+	}
+}
+
+func currentDay(now time.Time) string {
+	return now.UTC().Format("2006-01-02")
+}
+
+func (l *Limiter) Cost(sku string) float64 {
+	return l.config.Costs[sku]
+}
+
+// Reserva uma chamada do SKU: espera a vez pelo QPS e desconta o custo do
+// orçamento do dia. Sem orçamento devolve ErrBudgetExceeded, ou espera o
+// próximo dia quando BlockOnBudget está ligado. A espera termina com o ctx.
+func (l *Limiter) Acquire(ctx context.Context, sku string) error {
+	for {
+		wait, err := l.reserve(sku, time.Now())
+		if err != nil {
+			return err
+		}
+		if wait <= 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Devolve quanto esperar antes de tentar de novo, ou zero quando a chamada foi reservada
+func (l *Limiter) reserve(sku string, now time.Time) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day := currentDay(now); day != l.day {
+		l.day = day
+		l.spent = 0
+	}
+
+	cost := l.config.Costs[sku]
+	if l.config.DailyBudget > 0 && l.spent+cost > l.config.DailyBudget {
+		if !l.config.BlockOnBudget {
+			return 0, fmt.Errorf("%w: spent %.2f of %.2f USD", ErrBudgetExceeded, l.spent, l.config.DailyBudget)
+		}
+		tomorrow := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		log.Printf("Orçamento diário da API do Google esgotado, aguardando até %s", tomorrow.Format(time.RFC3339))
+		return tomorrow.Sub(now), nil
+	}
+
+	if qps := l.config.QPS[sku]; qps > 0 {
+		next := l.nextCall[sku]
+		if now.Before(next) {
+			return next.Sub(now), nil
+		}
+		l.nextCall[sku] = now.Add(time.Duration(float64(time.Second) / qps))
+	}
+
+	l.spent += cost
+	return 0, nil
+}
+
+func (l *Limiter) SpentToday() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if currentDay(time.Now()) != l.day {
+		return 0
+	}
+	return l.spent
+}
+
+// Chamado antes de cada chamada à API, com o SKU e o custo estimado
+type CallHandler func(sku string, cost float64)
+
+// Envolve um provider passando cada chamada pelo Limiter e avisando OnCall
+type MeteredProvider struct {
+	Provider PlacesProvider
+	Limiter  *Limiter
+	OnCall   CallHandler
+}
+
+func NewMeteredProvider(provider PlacesProvider, limiter *Limiter, onCall CallHandler) *MeteredProvider {
+	return &MeteredProvider{Provider: provider, Limiter: limiter, OnCall: onCall}
+}
+
+func (p *MeteredProvider) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	if err := p.acquire(ctx, SKUGeocoding); err != nil {
+		return GeocodeResult{}, err
+	}
+	return p.Provider.Geocode(ctx, address)
+}
+
+func (p *MeteredProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	if err := p.acquire(ctx, SKUTextSearch); err != nil {
+		return SearchPage{}, err
+	}
+	return p.Provider.Search(ctx, req)
+}
+
+func (p *MeteredProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	if err := p.acquire(ctx, SKUPlaceDetails); err != nil {
+		return nil, err
+	}
+	return p.Provider.Details(ctx, placeID)
+}
+
+// A chamada é contabilizada antes de ser feita: o Google cobra mesmo quando
+// a resposta é um erro
+func (p *MeteredProvider) acquire(ctx context.Context, sku string) error {
+	cost := DefaultSKUCosts[sku]
+	if p.Limiter != nil {
+		if err := p.Limiter.Acquire(ctx, sku); err != nil {
+			return err
+		}
+		cost = p.Limiter.Cost(sku)
+	}
+	if p.OnCall != nil {
+		p.OnCall(sku, cost)
+	}
+	return nil
+}
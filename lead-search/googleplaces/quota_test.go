@@ -0,0 +1,42 @@
+package googleplaces
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterFailsWhenBudgetIsSpent(t *testing.T) {
+	limiter := NewLimiter(LimiterConfig{
+		QPS:         map[string]float64{},
+		Costs:       map[string]float64{SKUTextSearch: 0.04},
+		DailyBudget: 0.1,
+	}, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Acquire(context.Background(), SKUTextSearch); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if err := limiter.Acquire(context.Background(), SKUTextSearch); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	// Um novo dia libera o orçamento
+	if wait, err := limiter.reserve(SKUTextSearch, time.Now().Add(24*time.Hour)); err != nil || wait != 0 {
+		t.Fatalf("expected budget reset on the next day, got wait %v err %v", wait, err)
+	}
+}
+
+func TestLimiterSpacesCallsByQPS(t *testing.T) {
+	limiter := NewLimiter(LimiterConfig{QPS: map[string]float64{SKUPlaceDetails: 10}, Costs: map[string]float64{}}, 0)
+
+	now := time.Now()
+	if wait, _ := limiter.reserve(SKUPlaceDetails, now); wait != 0 {
+		t.Fatalf("first call should not wait, got %v", wait)
+	}
+	if wait, _ := limiter.reserve(SKUPlaceDetails, now); wait != 100*time.Millisecond {
+		t.Fatalf("expected to wait 100ms, got %v", wait)
+	}
+}
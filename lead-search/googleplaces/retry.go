@@ -0,0 +1,108 @@
+package googleplaces
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// Backoff exponencial com jitter total: espera um valor aleatório entre zero
+// e BaseDelay*2^(attempt-1), limitado a MaxDelay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// Executa fn de novo enquanto o erro for retryable, até MaxAttempts tentativas
+// ou até o ctx ser cancelado
+func (p RetryPolicy) Do(ctx context.Context, op string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.Backoff(attempt)
+		log.Printf("Erro temporário em %s (tentativa %d/%d), tentando novamente em %s: %v", op, attempt, p.MaxAttempts, delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Envolve um provider repetindo as chamadas que falham com erro retryable.
+// Páginas da Text Search com status retryable (OVER_QUERY_LIMIT...) também
+// são repetidas.
+type RetryingProvider struct {
+	Provider PlacesProvider
+	Policy   RetryPolicy
+}
+
+func NewRetryingProvider(provider PlacesProvider, policy RetryPolicy) *RetryingProvider {
+	return &RetryingProvider{Provider: provider, Policy: policy}
+}
+
+func (p *RetryingProvider) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	var result GeocodeResult
+	err := p.Policy.Do(ctx, "geocode", func() error {
+		var err error
+		result, err = p.Provider.Geocode(ctx, address)
+		return err
+	})
+	return result, err
+}
+
+func (p *RetryingProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	var page SearchPage
+	err := p.Policy.Do(ctx, "search", func() error {
+		var err error
+		page, err = p.Provider.Search(ctx, req)
+		if err == nil && retryableStatuses[page.Status] {
+			return newStatusError("search", page.Status, page.ErrorMessage)
+		}
+		return err
+	})
+	return page, err
+}
+
+func (p *RetryingProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	var details map[string]interface{}
+	err := p.Policy.Do(ctx, "details", func() error {
+		var err error
+		details, err = p.Provider.Details(ctx, placeID)
+		return err
+	})
+	return details, err
+}
+
+// Espera d ou até o ctx ser cancelado, devolvendo o erro do ctx nesse caso
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
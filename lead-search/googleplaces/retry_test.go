@@ -0,0 +1,64 @@
+package googleplaces
+
+import (
+	"context"
+	"testing"
+)
+
+type flakyProvider struct {
+	FixtureProvider
+	searchPages []SearchPage
+	detailsErrs []error
+	calls       int
+}
+
+func (p *flakyProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	page := p.searchPages[p.calls]
+	p.calls++
+	return page, nil
+}
+
+func (p *flakyProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	err := p.detailsErrs[p.calls]
+	p.calls++
+	return nil, err
+}
+
+var noDelayPolicy = RetryPolicy{MaxAttempts: 3}
+
+func TestRetryingProviderRetriesOverQueryLimit(t *testing.T) {
+	flaky := &flakyProvider{searchPages: []SearchPage{
+		{Status: "OVER_QUERY_LIMIT"},
+		{Status: StatusOK, Results: []PlaceResult{{PlaceID: "abc"}}},
+	}}
+
+	page, err := NewRetryingProvider(flaky, noDelayPolicy).Search(context.Background(), SearchRequest{Query: "padaria"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if flaky.calls != 2 || len(page.Results) != 1 {
+		t.Errorf("expected a successful second call, got %d calls and %+v", flaky.calls, page)
+	}
+}
+
+func TestRetryingProviderStopsOnPermanentError(t *testing.T) {
+	flaky := &flakyProvider{detailsErrs: []error{
+		newStatusError("details", "NOT_FOUND", ""),
+		nil,
+	}}
+
+	_, err := NewRetryingProvider(flaky, noDelayPolicy).Details(context.Background(), "abc")
+	if err == nil || IsRetryable(err) || flaky.calls != 1 {
+		t.Errorf("expected one call with a permanent error, got %d calls and %v", flaky.calls, err)
+	}
+}
+
+func TestRetryingProviderGivesUpAfterMaxAttempts(t *testing.T) {
+	unavailable := newHTTPError("details", 503, "", "503 Service Unavailable")
+	flaky := &flakyProvider{detailsErrs: []error{unavailable, unavailable, unavailable}}
+
+	_, err := NewRetryingProvider(flaky, noDelayPolicy).Details(context.Background(), "abc")
+	if !IsRetryable(err) || flaky.calls != noDelayPolicy.MaxAttempts {
+		t.Errorf("expected %d calls ending in a retryable error, got %d calls and %v", noDelayPolicy.MaxAttempts, flaky.calls, err)
+	}
+}
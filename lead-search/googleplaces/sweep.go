@@ -0,0 +1,197 @@
+package googleplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// Quantidade máxima de resultados que a Text Search devolve para uma consulta.
+// Uma célula que atinge esse número provavelmente tem mais lugares escondidos.
+const FullSearchResults = MaxTextSearchPages * 20
+
+const (
+	metersPerDegreeLat    = 111320.0
+	defaultSweepMinRadius = 250
+)
+
+type LatLng struct {
+	Lat float64
+	Lng float64
+}
+
+func (l LatLng) String() string {
+	return fmt.Sprintf("%f,%f", l.Lat, l.Lng)
+}
+
+type Bounds struct {
+	NorthEast LatLng
+	SouthWest LatLng
+}
+
+// Círculo de busca da varredura. Cada célula cobre um quadrado de lado
+// Radius*√2 centrado em Center, então células vizinhas se sobrepõem nas bordas.
+type SweepCell struct {
+	Center LatLng
+	Radius int
+	Depth  int
+}
+
+type SweepOptions struct {
+	Radius      int // raio inicial das células, em metros
+	MinRadius   int // abaixo desse raio as células não são mais subdivididas
+	MaxCells    int // limite de células consultadas (0 = sem limite)
+	OnPage      PageHandler
+	OnCell      func(cell SweepCell, results int, newPlaces int)
+	OnCellError func(cell SweepCell, err error) // a varredura segue depois de uma célula com erro
+}
+
+func (s *Service) GeocodeBounds(ctx context.Context, address string) (Bounds, error) {
+	log.Printf("Buscando área para o endereço: %s", address)
+
+	result, err := s.Provider.Geocode(ctx, address)
+	if err != nil {
+		return Bounds{}, err
+	}
+	return result.Bounds, nil
+}
+
+// Divide a área em uma grade de círculos de raio radius que se sobrepõem
+func GridCells(bounds Bounds, radius int) []SweepCell {
+	if radius <= 0 {
+		return nil
+	}
+
+	step := float64(radius) * math.Sqrt2
+	midLat := (bounds.NorthEast.Lat + bounds.SouthWest.Lat) / 2
+	latStep := step / metersPerDegreeLat
+	lngStep := step / (metersPerDegreeLat * math.Cos(midLat*math.Pi/180))
+
+	rows := int(math.Ceil((bounds.NorthEast.Lat - bounds.SouthWest.Lat) / latStep))
+	cols := int(math.Ceil((bounds.NorthEast.Lng - bounds.SouthWest.Lng) / lngStep))
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 1 {
+		cols = 1
+	}
+
+	cells := make([]SweepCell, 0, rows*cols)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			cells = append(cells, SweepCell{
+				Center: LatLng{
+					Lat: bounds.SouthWest.Lat + (float64(row)+0.5)*latStep,
+					Lng: bounds.SouthWest.Lng + (float64(col)+0.5)*lngStep,
+				},
+				Radius: radius,
+			})
+		}
+	}
+	return cells
+}
+
+// Divide a célula em quatro quadrantes com metade do raio
+func (c SweepCell) Subdivide() []SweepCell {
+	offset := float64(c.Radius) * math.Sqrt2 / 4
+	latOffset := offset / metersPerDegreeLat
+	lngOffset := offset / (metersPerDegreeLat * math.Cos(c.Center.Lat*math.Pi/180))
+
+	children := make([]SweepCell, 0, 4)
+	for _, dLat := range []float64{-latOffset, latOffset} {
+		for _, dLng := range []float64{-lngOffset, lngOffset} {
+			children = append(children, SweepCell{
+				Center: LatLng{Lat: c.Center.Lat + dLat, Lng: c.Center.Lng + dLng},
+				Radius: c.Radius / 2,
+				Depth:  c.Depth + 1,
+			})
+		}
+	}
+	return children
+}
+
+// Varre a área inteira com a Text Search. Células que voltam com o máximo de
+// resultados são subdivididas, e os lugares repetidos entre células são descartados.
+// Uma célula com erro é reportada em OnCellError e não interrompe a varredura;
+// só falha a varredura em que nenhuma célula deu certo.
+func (s *Service) Sweep(ctx context.Context, query string, bounds Bounds, opts SweepOptions) ([]map[string]interface{}, error) {
+	if opts.MinRadius <= 0 {
+		opts.MinRadius = defaultSweepMinRadius
+	}
+
+	pending := GridCells(bounds, opts.Radius)
+	log.Printf("Varredura de %s iniciada com %d células de raio %dm", query, len(pending), opts.Radius)
+
+	seen := make(map[string]bool)
+	var allPlaces []map[string]interface{}
+	cellsSearched := 0
+	cellsFailed := 0
+	var lastErr error
+
+	for len(pending) > 0 {
+		if opts.MaxCells > 0 && cellsSearched >= opts.MaxCells {
+			log.Printf("Limite de %d células atingido, %d células não consultadas", opts.MaxCells, len(pending))
+			break
+		}
+
+		cell := pending[0]
+		pending = pending[1:]
+
+		places, err := s.SearchPlaces(ctx, query, cell.Center.String(), cell.Radius, MaxTextSearchPages, 0, opts.OnPage)
+		if ctx.Err() != nil {
+			allPlaces = appendUnseen(allPlaces, places, seen)
+			log.Printf("Varredura de %s interrompida com %d células pendentes", query, len(pending)+1)
+			return allPlaces, ctx.Err()
+		}
+		cellsSearched++
+		if err != nil {
+			allPlaces = appendUnseen(allPlaces, places, seen)
+			err = fmt.Errorf("error sweeping cell %s (radius %d): %w", cell.Center, cell.Radius, err)
+			// Sem orçamento as outras células falhariam do mesmo jeito
+			if errors.Is(err, ErrBudgetExceeded) {
+				return allPlaces, err
+			}
+			log.Printf("Falha na célula %s, seguindo com a varredura: %v", cell.Center, err)
+			cellsFailed++
+			lastErr = err
+			if opts.OnCellError != nil {
+				opts.OnCellError(cell, err)
+			}
+			continue
+		}
+
+		before := len(allPlaces)
+		allPlaces = appendUnseen(allPlaces, places, seen)
+		newPlaces := len(allPlaces) - before
+
+		if len(places) >= FullSearchResults && cell.Radius/2 >= opts.MinRadius {
+			log.Printf("Célula %s retornou %d resultados, subdividindo", cell.Center, len(places))
+			pending = append(pending, cell.Subdivide()...)
+		}
+
+		if opts.OnCell != nil {
+			opts.OnCell(cell, len(places), newPlaces)
+		}
+	}
+
+	log.Printf("Varredura concluída: %d células consultadas, %d com erro, %d lugares únicos", cellsSearched, cellsFailed, len(allPlaces))
+	if cellsFailed > 0 && cellsFailed == cellsSearched {
+		return allPlaces, fmt.Errorf("all %d cells failed: %w", cellsFailed, lastErr)
+	}
+	return allPlaces, nil
+}
+
+// Acrescenta os lugares ainda não vistos em outras células
+func appendUnseen(allPlaces []map[string]interface{}, places []map[string]interface{}, seen map[string]bool) []map[string]interface{} {
+	for _, place := range places {
+		placeID, _ := place["PlaceID"].(string)
+		if placeID == "" || seen[placeID] {
+			continue
+		}
+		seen[placeID] = true
+		allPlaces = append(allPlaces, place)
+	}
+	return allPlaces
+}
@@ -0,0 +1,114 @@
+package googleplaces
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGridCellsCoversBounds(t *testing.T) {
+	bounds := Bounds{
+		NorthEast: LatLng{Lat: -23.50, Lng: -46.60},
+		SouthWest: LatLng{Lat: -23.60, Lng: -46.70},
+	}
+
+	cells := GridCells(bounds, 2000)
+	if len(cells) == 0 {
+		t.Fatal("expected grid cells, got none")
+	}
+
+	// Cada ponto da área precisa estar dentro de pelo menos um círculo
+	for lat := bounds.SouthWest.Lat; lat <= bounds.NorthEast.Lat; lat += 0.005 {
+		for lng := bounds.SouthWest.Lng; lng <= bounds.NorthEast.Lng; lng += 0.005 {
+			covered := false
+			for _, cell := range cells {
+				if distanceMeters(cell.Center, LatLng{Lat: lat, Lng: lng}) <= float64(cell.Radius) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				t.Fatalf("point %f,%f is not covered by any cell", lat, lng)
+			}
+		}
+	}
+}
+
+func TestSubdivideHalvesRadius(t *testing.T) {
+	cell := SweepCell{Center: LatLng{Lat: -23.55, Lng: -46.63}, Radius: 2000}
+
+	children := cell.Subdivide()
+	if len(children) != 4 {
+		t.Fatalf("expected 4 children, got %d", len(children))
+	}
+	for _, child := range children {
+		if child.Radius != 1000 || child.Depth != 1 {
+			t.Errorf("unexpected child %+v", child)
+		}
+		if d := distanceMeters(cell.Center, child.Center); d > float64(cell.Radius) {
+			t.Errorf("child center %s is %.0fm away from parent, outside radius", child.Center, d)
+		}
+	}
+}
+
+func distanceMeters(a LatLng, b LatLng) float64 {
+	dLat := (a.Lat - b.Lat) * metersPerDegreeLat
+	dLng := (a.Lng - b.Lng) * metersPerDegreeLat * math.Cos(a.Lat*math.Pi/180)
+	return math.Sqrt(dLat*dLat + dLng*dLng)
+}
+
+// Provider que devolve um lugar por célula e falha na célula failLocation
+type cellProvider struct {
+	FixtureProvider
+	failLocation string
+}
+
+func (p *cellProvider) Search(ctx context.Context, req SearchRequest) (SearchPage, error) {
+	if req.Location == p.failLocation {
+		return SearchPage{}, errors.New("connection reset")
+	}
+	return SearchPage{Status: StatusOK, Results: []PlaceResult{{PlaceID: req.Location}}}, nil
+}
+
+func TestSweepContinuesAfterCellError(t *testing.T) {
+	bounds := Bounds{
+		NorthEast: LatLng{Lat: -23.50, Lng: -46.60},
+		SouthWest: LatLng{Lat: -23.60, Lng: -46.70},
+	}
+	cells := GridCells(bounds, 2000)
+	service := NewServiceWithProvider(&cellProvider{failLocation: cells[0].Center.String()})
+
+	var failed []SweepCell
+	places, err := service.Sweep(context.Background(), "padaria", bounds, SweepOptions{
+		Radius: 2000,
+		OnCellError: func(cell SweepCell, err error) {
+			failed = append(failed, cell)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(failed) != 1 || failed[0] != cells[0] {
+		t.Errorf("expected only the first cell to fail, got %v", failed)
+	}
+	if len(places) != len(cells)-1 {
+		t.Errorf("expected %d places, got %d", len(cells)-1, len(places))
+	}
+}
+
+func TestSweepFailsWhenEveryCellFails(t *testing.T) {
+	bounds := Bounds{
+		NorthEast: LatLng{Lat: -23.55, Lng: -46.63},
+		SouthWest: LatLng{Lat: -23.56, Lng: -46.64},
+	}
+	cells := GridCells(bounds, 2000)
+	if len(cells) != 1 {
+		t.Fatalf("expected a single cell, got %d", len(cells))
+	}
+	service := NewServiceWithProvider(&cellProvider{failLocation: cells[0].Center.String()})
+
+	if _, err := service.Sweep(context.Background(), "padaria", bounds, SweepOptions{Radius: 2000}); err == nil {
+		t.Error("expected an error when every cell fails")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import "shared/leadmessage"
+
+// Monta a mensagem publicada a partir dos detalhes do lugar. Campos que a
+// chamada de Details não traz (a API legada não devolve avaliações, preço e
+// tipos) vêm do resultado da Text Search, quando houver.
+func newLeadMessage(details map[string]interface{}, fromSearch map[string]interface{}, category string, searchCity string, radius int) leadmessage.LeadMessage {
+	field := func(key string) interface{} {
+		if value, ok := details[key]; ok && !isZeroField(value) {
+			return value
+		}
+		return fromSearch[key]
+	}
+
+	return leadmessage.LeadMessage{
+		SchemaVersion:            leadmessage.SchemaVersion,
+		PlaceID:                  stringField(field("PlaceID")),
+		Name:                     stringField(field("Name")),
+		FormattedAddress:         stringField(details["FormattedAddress"]),
+		City:                     stringField(details["City"]),
+		State:                    stringField(details["State"]),
+		ZIPCode:                  stringField(details["ZIPCode"]),
+		Country:                  stringField(details["Country"]),
+		InternationalPhoneNumber: stringField(details["InternationalPhoneNumber"]),
+		Website:                  stringField(details["Website"]),
+		Description:              stringField(details["Description"]),
+		Rating:                   floatField(field("Rating")),
+		UserRatingsTotal:         intField(field("UserRatingsTotal")),
+		PriceLevel:               intField(field("PriceLevel")),
+		BusinessStatus:           stringField(field("BusinessStatus")),
+		Vicinity:                 stringField(field("Vicinity")),
+		PermanentlyClosed:        boolField(field("PermanentlyClosed")),
+		Types:                    stringsField(field("Types")),
+		OpeningHours:             stringsField(details["OpeningHours"]),
+		Category:                 category,
+		SearchCity:               searchCity,
+		Radius:                   radius,
+	}
+}
+
+// Índice dos resultados da Text Search pelo place ID
+func searchResultsByPlaceID(places []map[string]interface{}) map[string]map[string]interface{} {
+	byID := make(map[string]map[string]interface{}, len(places))
+	for _, place := range places {
+		if placeID, _ := place["PlaceID"].(string); placeID != "" {
+			byID[placeID] = place
+		}
+	}
+	return byID
+}
+
+func isZeroField(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case int:
+		return v == 0
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+func stringField(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func floatField(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+func intField(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func boolField(value interface{}) bool {
+	b, _ := value.(bool)
+	return b
+}
+
+func stringsField(value interface{}) []string {
+	s, _ := value.([]string)
+	return s
+}
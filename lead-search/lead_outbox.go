@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"lead-search/repository"
+
+	"shared/leadmessage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const outboxReplayBatchSize = 100
+
+var outboxPending = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "leadsearch_outbox_pending",
+		Help: "Leads gravados no outbox esperando confirmação do RabbitMQ",
+	},
+)
+
+var publishFailures = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "leadsearch_publish_failures_total",
+		Help: "Publicações no RabbitMQ que falharam e ficaram no outbox",
+	},
+)
+
+// Destino das mensagens do outbox, já serializadas
+type bodyPublisher interface {
+	PublishBody(body []byte) error
+}
+
+// Grava cada lead em lead_outbox antes de publicar e só apaga depois da
+// confirmação do broker. Se a publicação falhar o lead fica no outbox e é
+// reenviado por Start; para quem chamou Publish ele já está seguro.
+type outboxPublisher struct {
+	db     *sql.DB
+	target bodyPublisher
+
+	// Publish segura o lock do insert ao delete e o reenvio lê cada lote com
+	// ele, então o reenvio nunca pega uma mensagem que Publish ainda está enviando
+	mu sync.Mutex
+}
+
+func newOutboxPublisher(db *sql.DB, target bodyPublisher) *outboxPublisher {
+	return &outboxPublisher{db: db, target: target}
+}
+
+func (p *outboxPublisher) Publish(lead leadmessage.LeadMessage) error {
+	// Mensagens inválidas não entram no outbox: reenviar não as corrigiria
+	body, err := leadmessage.Encode(lead)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize lead data: %v", err)
+	}
+	placeID := lead.PlaceID
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, err := repository.InsertOutboxMessage(p.db, placeID, body)
+	if err != nil {
+		return err
+	}
+
+	err = p.target.PublishBody(body)
+	if err != nil {
+		publishFailures.Inc()
+		log.Printf("Erro ao publicar o lead %s, mantido no outbox para reenvio: %v", placeID, err)
+		if err := repository.RecordOutboxFailure(p.db, id, err.Error()); err != nil {
+			log.Printf("%v", err)
+		}
+		p.refreshPending()
+		return nil
+	}
+
+	return repository.DeleteOutboxMessage(p.db, id)
+}
+
+// Reenvia o outbox logo na subida e depois a cada intervalo, até o ctx acabar
+func (p *outboxPublisher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.replay()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("Reenvio do outbox de leads iniciado a cada %s", interval)
+}
+
+// Publica as mensagens pendentes em ordem e para na primeira falha, que
+// quase sempre indica que o RabbitMQ continua fora. O lock fica preso só na
+// leitura do lote e em cada delete, para não travar as buscas que publicam
+// enquanto o outbox é reenviado.
+func (p *outboxPublisher) replay() {
+	defer p.refreshPending()
+
+	for {
+		p.mu.Lock()
+		messages, err := repository.ListOutboxMessages(p.db, outboxReplayBatchSize)
+		p.mu.Unlock()
+		if err != nil {
+			log.Printf("Erro ao carregar o outbox de leads: %v", err)
+			return
+		}
+		if len(messages) == 0 {
+			return
+		}
+
+		for _, message := range messages {
+			if err := p.target.PublishBody(message.Body); err != nil {
+				log.Printf("Reenvio do outbox interrompido no lead %s: %v", message.PlaceID, err)
+				if err := repository.RecordOutboxFailure(p.db, message.ID, err.Error()); err != nil {
+					log.Printf("%v", err)
+				}
+				return
+			}
+			p.mu.Lock()
+			err := repository.DeleteOutboxMessage(p.db, message.ID)
+			p.mu.Unlock()
+			if err != nil {
+				log.Printf("%v", err)
+				return
+			}
+		}
+		log.Printf("%d leads do outbox reenviados", len(messages))
+	}
+}
+
+func (p *outboxPublisher) refreshPending() {
+	count, err := repository.CountOutboxMessages(p.db)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	outboxPending.Set(float64(count))
+}
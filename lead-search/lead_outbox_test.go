@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"lead-search/repository"
+
+	"shared/leadmessage"
+)
+
+// Broker de mentira que recusa as mensagens enquanto down estiver ligado
+type flakyBroker struct {
+	down      bool
+	delivered [][]byte
+}
+
+func (b *flakyBroker) PublishBody(body []byte) error {
+	if b.down {
+		return errors.New("connection refused")
+	}
+	b.delivered = append(b.delivered, body)
+	return nil
+}
+
+func TestOutboxKeepsLeadsUntilBrokerConfirms(t *testing.T) {
+	db := setupTestDatabase(t)
+	broker := &flakyBroker{down: true}
+	publisher := newOutboxPublisher(db, broker)
+
+	for _, placeID := range []string{"place_a", "place_b"} {
+		if err := publisher.Publish(leadmessage.LeadMessage{PlaceID: placeID, Name: "Padaria"}); err != nil {
+			t.Fatalf("Publish %s: %v", placeID, err)
+		}
+	}
+	if count, _ := repository.CountOutboxMessages(db); count != 2 {
+		t.Fatalf("expected 2 leads in the outbox while the broker is down, got %d", count)
+	}
+
+	// Continua fora: nada sai do outbox
+	publisher.replay()
+	if count, _ := repository.CountOutboxMessages(db); count != 2 {
+		t.Fatalf("expected the outbox to keep 2 leads, got %d", count)
+	}
+
+	broker.down = false
+	publisher.replay()
+	if count, _ := repository.CountOutboxMessages(db); count != 0 {
+		t.Errorf("expected the outbox to be empty after replay, got %d", count)
+	}
+	if len(broker.delivered) != 2 {
+		t.Fatalf("expected 2 leads replayed, got %d", len(broker.delivered))
+	}
+	first, err := leadmessage.Decode(int32(leadmessage.SchemaVersion), broker.delivered[0])
+	if err != nil || first.PlaceID != "place_a" {
+		t.Errorf("expected leads replayed in order, got %+v (err %v)", first, err)
+	}
+
+	if err := publisher.Publish(leadmessage.LeadMessage{PlaceID: "place_c", Name: "Padaria"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if count, _ := repository.CountOutboxMessages(db); count != 0 || len(broker.delivered) != 3 {
+		t.Errorf("expected confirmed lead to leave the outbox, got %d pending and %d delivered", count, len(broker.delivered))
+	}
+}
+
+// Broker de mentira que segura a primeira mensagem até release ser fechado
+type slowBroker struct {
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *slowBroker) PublishBody(body []byte) error {
+	b.mu.Lock()
+	b.calls++
+	first := b.calls == 1
+	b.mu.Unlock()
+	if first {
+		close(b.started)
+		<-b.release
+	}
+	return nil
+}
+
+// Publish não espera o reenvio do outbox terminar
+func TestOutboxReplayDoesNotBlockPublish(t *testing.T) {
+	db := setupTestDatabase(t)
+	if _, err := repository.InsertOutboxMessage(db, "place_a", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	broker := &slowBroker{started: make(chan struct{}), release: make(chan struct{})}
+	publisher := newOutboxPublisher(db, broker)
+
+	replayed := make(chan struct{})
+	go func() {
+		publisher.replay()
+		close(replayed)
+	}()
+	<-broker.started
+
+	published := make(chan error, 1)
+	go func() {
+		published <- publisher.Publish(leadmessage.LeadMessage{PlaceID: "place_b", Name: "Padaria"})
+	}()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked while the outbox was being replayed")
+	}
+
+	close(broker.release)
+	<-replayed
+	if count, _ := repository.CountOutboxMessages(db); count != 0 {
+		t.Errorf("expected the outbox to be empty, got %d", count)
+	}
+}
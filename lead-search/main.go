@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,6 +11,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"lead-search/googleplaces"
@@ -17,7 +21,6 @@ import (
 	"database/sql"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/streadway/amqp"
 
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus"
@@ -84,12 +87,13 @@ var (
 		[]string{"category_id"},
 	)
 
-	geocodingDuration = prometheus.NewHistogram(
+	geocodingDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "geocoding_duration_seconds",
 			Help:    "Duração da geocodificação do CEP.",
 			Buckets: prometheus.DefBuckets,
 		},
+		[]string{"cache"},
 	)
 )
 
@@ -101,34 +105,85 @@ func main() {
 
 	log.Println("Starting the service...")
 
-	conn, ch, err := connectToRabbitMQ()
-	if err != nil {
-		log.Fatalf("Erro ao conectar ao RabbitMQ: %v", err)
-	}
-	defer conn.Close()
-	defer ch.Close()
+	// SIGTERM (docker stop) e SIGINT iniciam o desligamento
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	err = godotenv.Load()
+	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 	log.Println(".env file loaded successfully")
 
-	apiKey := os.Getenv("GOOGLE_PLACES_API_KEY")
-
-	if apiKey == "" {
-		log.Fatal("API key is required. Set the GOOGLE_PLACES_API_KEY environment variable.")
+	provider, err := newPlacesProvider(os.Getenv("GOOGLE_PLACES_API_KEY"))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	db, err := setupDatabase()
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "/usr/src/app/data/geo.db"
+	}
+	db, err := setupDatabase(dbPath)
 	if err != nil {
 		log.Fatalf("Erro ao configurar o banco de dados: %v", err)
 	}
 	defer db.Close()
 
+	tokensFile := os.Getenv("NEXT_PAGE_TOKENS_FILE")
+	if tokensFile == "" {
+		tokensFile = "/app/lead-search/next_page_tokens.json"
+	}
+	err = googleplaces.MigrateTokenFile(repository.NewQueryProgressStore(db), tokensFile)
+	if err != nil {
+		log.Printf("Erro ao migrar o arquivo de tokens %s: %v", tokensFile, err)
+	}
+
+	rabbitURL, err := rabbitMQURL()
+	if err != nil {
+		log.Fatalf("Erro ao configurar o RabbitMQ: %v", err)
+	}
+	rabbit := newRabbitPublisher(rabbitURL, getEnvDuration("PUBLISH_CONFIRM_TIMEOUT", 10*time.Second))
+	defer rabbit.Close()
+	if err := rabbit.Connect(5, 10*time.Second); err != nil {
+		log.Printf("RabbitMQ indisponível, os leads ficam no outbox até a conexão voltar: %v", err)
+	}
+	publisher := newOutboxPublisher(db, rabbit)
+	publisher.Start(ctx, getEnvDuration("OUTBOX_REPLAY_INTERVAL", 30*time.Second))
+
+	queue := newSearchQueue(db, provider, newAPILimiter(db), publisher, getEnvInt("SEARCH_WORKERS", 2), getEnvInt("SEARCH_QUEUE_SIZE", 100))
+	queue.Resume()
+	scheduler := newSearchScheduler(db, queue)
+	scheduler.Start()
+
+	retryWorker := &placeRetryWorker{
+		db:          db,
+		provider:    provider,
+		limiter:     queue.limiter,
+		publisher:   queue.publisher,
+		maxAttempts: getEnvInt("PLACE_RETRY_MAX_ATTEMPTS", 6),
+	}
+	retryWorker.Start(ctx, getEnvDuration("PLACE_RETRY_INTERVAL", 5*time.Minute))
+
 	http.Handle("/metrics", promhttp.Handler())
 	http.HandleFunc("/start-search", func(w http.ResponseWriter, r *http.Request) {
-		startSearchHandler(w, r, db, ch)
+		startSearchHandler(w, r, db, queue)
+	})
+	http.HandleFunc("/searches/batch", func(w http.ResponseWriter, r *http.Request) {
+		batchSearchHandler(w, r, db, queue)
+	})
+	http.HandleFunc("/searches/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			cancelSearchHandler(w, r, db, queue)
+			return
+		}
+		searchStatusHandler(w, r, db)
+	})
+	http.HandleFunc("/schedules", func(w http.ResponseWriter, r *http.Request) {
+		schedulesHandler(w, r, db)
+	})
+	http.HandleFunc("/schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		scheduleHandler(w, r, db)
 	})
 
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -140,48 +195,93 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
-	log.Println("Starting server on port 8082...")
-	err = http.ListenAndServe(":8082", nil)
-	if err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
-	}
+	server := &http.Server{Addr: ":8082"}
+	go func() {
+		log.Println("Starting server on port 8082...")
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start HTTP server: %v", err)
+		}
+	}()
 
-}
+	<-ctx.Done()
+	stop()
+	log.Println("Desligando o serviço...")
 
-func connectToRabbitMQ() (*amqp.Connection, *amqp.Channel, error) {
-	log.Println("Conectando ao RabbitMQ...")
-	rabbitmqHost := os.Getenv("RABBITMQ_HOST")
-	rabbitmqPort := os.Getenv("RABBITMQ_PORT")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), getEnvDuration("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancel()
 
-	if rabbitmqHost == "" || rabbitmqPort == "" {
-		return nil, nil, fmt.Errorf("RABBITMQ_HOST and RABBITMQ_PORT must be set")
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Erro ao encerrar o servidor HTTP: %v", err)
+	}
+	// O agendador para antes da fila para não enfileirar buscas durante o desligamento
+	select {
+	case <-scheduler.Stop().Done():
+	case <-shutdownCtx.Done():
+		log.Printf("Erro ao encerrar o agendador de buscas: %v", shutdownCtx.Err())
 	}
+	if err := queue.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Erro ao encerrar a fila de buscas: %v", err)
+	}
+	log.Println("Serviço encerrado")
+}
 
-	log.Printf("Conectado ao RabbitMQ em %s:%s", rabbitmqHost, rabbitmqPort)
+// Três páginas completas da Text Search
+const defaultMaxResults = 60
 
-	var conn *amqp.Connection
-	var ch *amqp.Channel
-	var err error
+// Coordenadas de CEP mudam raramente; GEOCODE_CACHE_TTL=0 mantém o cache para sempre
+const defaultGeocodeCacheTTL = 30 * 24 * time.Hour
 
-	for i := 0; i < 5; i++ {
-		conn, err = amqp.Dial(fmt.Sprintf("amqp://guest:guest@%s:%s/", rabbitmqHost, rabbitmqPort))
-		if err == nil {
+const (
+	searchModeRadius = "radius"
+	searchModeSweep  = "sweep"
+)
 
-			ch, err = conn.Channel()
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to open RabbitMQ channel: %v", err)
-			}
-			return conn, ch, nil
-		}
+func getEnvInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
 
-		log.Printf("Failed to connect to RabbitMQ at %s:%s, retrying in 10 seconds... (%d/5)", rabbitmqHost, rabbitmqPort, i+1)
-		time.Sleep(10 * time.Second)
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %d", name, value, defaultValue)
+		return defaultValue
 	}
+	return parsed
+}
+
+// Aceita valores como "720h" ou "30m"
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s: %s, usando %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func cacheLabel(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
 
-	return conn, ch, nil
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Printf("Erro ao serializar resposta JSON: %v", err)
+	}
 }
 
-func startSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, ch *amqp.Channel) {
+func startSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, queue *searchQueue) {
 	startTime := time.Now()
 	totalRequests.WithLabelValues("/start-search", r.Method).Inc()
 
@@ -192,14 +292,19 @@ func startSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, ch *
 	}
 
 	categoryID := r.URL.Query().Get("category_id")
-	zipcodeIDString := r.URL.Query().Get("zipcode_id")
-	if zipcodeIDString == "" {
-		totalErrors.WithLabelValues("/start-search", "missing_zipcode_id").Inc()
-		http.Error(w, "Missing zipcode_id", http.StatusBadRequest)
-		return
-	}
 	radius := r.URL.Query().Get("radius")
 	maxResultsStr := r.URL.Query().Get("max_results")
+	maxPagesStr := r.URL.Query().Get("max_pages")
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = searchModeRadius
+	}
+	if mode != searchModeRadius && mode != searchModeSweep {
+		totalErrors.WithLabelValues("/start-search", "invalid_mode").Inc()
+		http.Error(w, "Invalid mode value, must be radius or sweep", http.StatusBadRequest)
+		return
+	}
 
 	if categoryID == "" || radius == "" {
 		totalErrors.WithLabelValues("/start-search", "missing_params").Inc()
@@ -214,44 +319,218 @@ func startSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, ch *
 		return
 	}
 
-	zipcodeID, err := strconv.Atoi(zipcodeIDString)
-	if err != nil {
-		totalErrors.WithLabelValues("/start-search", "invalid_zipcode_id").Inc()
-		http.Error(w, "Invalid zipcode_id value", http.StatusBadRequest)
+	var target [3]int
+	for i, name := range []string{"zipcode_id", "district_id", "city_id"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		target[i], err = strconv.Atoi(value)
+		if err != nil {
+			totalErrors.WithLabelValues("/start-search", "invalid_"+name).Inc()
+			http.Error(w, fmt.Sprintf("Invalid %s value", name), http.StatusBadRequest)
+			return
+		}
+	}
+	zipcodeID, districtID, cityID := target[0], target[1], target[2]
+
+	// O modo radius continua exigindo um CEP; a varredura aceita cidade ou bairro
+	if zipcodeID == 0 && (mode == searchModeRadius || (districtID == 0 && cityID == 0)) {
+		totalErrors.WithLabelValues("/start-search", "missing_zipcode_id").Inc()
+		if mode == searchModeRadius {
+			http.Error(w, "Missing zipcode_id", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Missing zipcode_id, district_id or city_id", http.StatusBadRequest)
+		}
 		return
 	}
 
-	maxResults := 1
+	maxResults := defaultMaxResults
+	if mode == searchModeSweep {
+		maxResults = 0
+	}
 	if maxResultsStr != "" {
 		maxResults, err = strconv.Atoi(maxResultsStr)
-		if err != nil {
+		if err != nil || maxResults <= 0 {
 			totalErrors.WithLabelValues("/start-search", "invalid_max_results").Inc()
 			http.Error(w, "Invalid max_results value", http.StatusBadRequest)
 			return
 		}
 	}
 
-	err = startSearch(categoryID, zipcodeID, radiusInt, maxResults, db, ch)
+	maxPages := googleplaces.MaxTextSearchPages
+	if maxPagesStr != "" {
+		maxPages, err = strconv.Atoi(maxPagesStr)
+		if err != nil || maxPages <= 0 || maxPages > googleplaces.MaxTextSearchPages {
+			totalErrors.WithLabelValues("/start-search", "invalid_max_pages").Inc()
+			http.Error(w, fmt.Sprintf("Invalid max_pages value, must be between 1 and %d", googleplaces.MaxTextSearchPages), http.StatusBadRequest)
+			return
+		}
+	}
+
+	job := searchJob{
+		Mode:       mode,
+		CategoryID: categoryID,
+		ZipcodeID:  zipcodeID,
+		DistrictID: districtID,
+		CityID:     cityID,
+		Radius:     radiusInt,
+		MaxResults: maxResults,
+		MaxPages:   maxPages,
+	}
+
+	locationInfo, err := resolveSearchLocation(db, job)
+	if err != nil {
+		totalErrors.WithLabelValues("/start-search", "get_location_info").Inc()
+		http.Error(w, fmt.Sprintf("Failed to get location info: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	progressID, err := repository.InsertSearchProgress(db, searchProgressFor(job, locationInfo))
 	if err != nil {
-		totalErrors.WithLabelValues("/start-search", "search_failed").Inc()
-		http.Error(w, fmt.Sprintf("Failed to start search: %v", err), http.StatusInternalServerError)
+		totalErrors.WithLabelValues("/start-search", "insert_progress").Inc()
+		http.Error(w, fmt.Sprintf("Failed to insert search progress: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	job.ProgressID = progressID
+	markSearchScheduled(db, job)
+	err = queue.Enqueue(job)
+	if err != nil {
+		totalErrors.WithLabelValues("/start-search", "queue_full").Inc()
+		repository.RecordSearchError(db, progressID, err.Error())
+		repository.UpdateSearchProgressStatus(db, progressID, repository.SearchStatusFailed)
+		http.Error(w, fmt.Sprintf("Failed to start search: %v", err), http.StatusServiceUnavailable)
 		return
 	}
 
 	processingDuration.WithLabelValues("/start-search").Observe(time.Since(startTime).Seconds())
-	fmt.Fprintf(w, "Search started for categoryID: %s, zipcodeID: %d, radius: %d", categoryID, zipcodeID, radiusInt)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job_id": progressID,
+		"mode":   mode,
+		"status": repository.SearchStatusQueued,
+	})
+}
+
+type searchStatusResponse struct {
+	JobID          int64          `json:"job_id"`
+	ScheduleID     int64          `json:"schedule_id,omitempty"`
+	Status         string         `json:"status"`
+	CategoryID     string         `json:"category_id"`
+	CityID         string         `json:"city_id"`
+	DistrictID     string         `json:"district_id,omitempty"`
+	ZipcodeID      string         `json:"zipcode_id,omitempty"`
+	Radius         int            `json:"radius"`
+	PagesFetched   int            `json:"pages_fetched"`
+	LeadsPublished int            `json:"leads_published"`
+	PlacesSkipped  int            `json:"places_skipped"`
+	ErrorCount     int            `json:"error_count"`
+	LastError      string         `json:"last_error,omitempty"`
+	APICalls       map[string]int `json:"api_calls"`
+	EstimatedCost  float64        `json:"estimated_cost_usd"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Cancela uma busca na fila ou em execução. Os leads já publicados continuam publicados.
+func cancelSearchHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, queue *searchQueue) {
+	totalRequests.WithLabelValues("/searches", r.Method).Inc()
+
+	progressID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		totalErrors.WithLabelValues("/searches", "invalid_id").Inc()
+		http.Error(w, "Invalid search id", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := repository.GetSearchProgressByID(db, progressID); err != nil {
+		totalErrors.WithLabelValues("/searches", "not_found").Inc()
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	running, err := queue.Cancel(progressID)
+	if err != nil {
+		totalErrors.WithLabelValues("/searches", "cancel").Inc()
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Em execução o status só muda quando o worker para a busca
+	if running {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"job_id": progressID,
+			"status": "cancelling",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"job_id": progressID,
+		"status": repository.SearchStatusCancelled,
+	})
 }
 
-func setupDatabase() (*sql.DB, error) {
-	dbPath := "/usr/src/app/data/geo.db"
+func searchStatusHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	totalRequests.WithLabelValues("/searches", r.Method).Inc()
 
+	if r.Method != http.MethodGet {
+		totalErrors.WithLabelValues("/searches", "invalid_method").Inc()
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	progressID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		totalErrors.WithLabelValues("/searches", "invalid_id").Inc()
+		http.Error(w, "Invalid search id", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := repository.GetSearchProgressByID(db, progressID)
+	if err != nil {
+		totalErrors.WithLabelValues("/searches", "not_found").Inc()
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	costs, err := repository.GetSearchAPICosts(db, progressID)
+	if err != nil {
+		log.Printf("Erro ao carregar os custos da busca %d: %v", progressID, err)
+	}
+	apiCalls := make(map[string]int)
+	estimatedCost := 0.0
+	for _, cost := range costs {
+		apiCalls[cost.SKU] = cost.Calls
+		estimatedCost += cost.EstimatedCost
+	}
+
+	writeJSON(w, http.StatusOK, searchStatusResponse{
+		JobID:          progress.ID,
+		ScheduleID:     progress.ScheduleID,
+		Status:         progress.Status,
+		CategoryID:     progress.CategoriaID,
+		CityID:         progress.CityID,
+		DistrictID:     progress.DistrictID,
+		ZipcodeID:      progress.ZipcodeID,
+		Radius:         progress.Radius,
+		PagesFetched:   progress.PagesFetched,
+		LeadsPublished: progress.LeadsExtracted,
+		PlacesSkipped:  progress.PlacesSkipped,
+		ErrorCount:     progress.ErrorCount,
+		LastError:      progress.LastError,
+		APICalls:       apiCalls,
+		EstimatedCost:  estimatedCost,
+		CreatedAt:      progress.SearchDate,
+	})
+}
+
+func setupDatabase(dbPath string) (*sql.DB, error) {
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		log.Printf("Banco de dados não encontrado, criando novo em: %s", dbPath)
 	} else {
 		log.Printf("Banco de dados já existe em: %s", dbPath)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
 	if err != nil {
 		return nil, err
 	}
@@ -315,6 +594,14 @@ func setupDatabase() (*sql.DB, error) {
             leads_extracted INTEGER,
             next_page_token TEXT
         );
+        CREATE TABLE IF NOT EXISTS geocode_cache (
+            zip_code TEXT PRIMARY KEY,
+            lat REAL NOT NULL,
+            lng REAL NOT NULL,
+            formatted_address TEXT,
+            source TEXT,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        );
         CREATE TABLE IF NOT EXISTS search_progress (
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             categoria_id INTEGER,
@@ -334,6 +621,63 @@ func setupDatabase() (*sql.DB, error) {
             FOREIGN KEY(district_id) REFERENCES district(id),
             FOREIGN KEY(zipcode_id) REFERENCES zipcode(id)
         );
+        CREATE TABLE IF NOT EXISTS api_cost_ledger (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            search_progress_id INTEGER,
+            sku TEXT NOT NULL,
+            day TEXT NOT NULL, -- dia UTC no formato AAAA-MM-DD
+            calls INTEGER DEFAULT 0,
+            estimated_cost REAL DEFAULT 0,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(search_progress_id, sku, day),
+            FOREIGN KEY(search_progress_id) REFERENCES search_progress(id)
+        );
+        CREATE TABLE IF NOT EXISTS place_retry (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            search_progress_id INTEGER,
+            place_id TEXT NOT NULL,
+            attempts INTEGER DEFAULT 0,
+            last_error TEXT,
+            status TEXT DEFAULT 'pending',
+            next_attempt_at TIMESTAMP,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(search_progress_id, place_id),
+            FOREIGN KEY(search_progress_id) REFERENCES search_progress(id)
+        );
+        CREATE TABLE IF NOT EXISTS lead_outbox (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            place_id TEXT,
+            body TEXT NOT NULL, -- lead serializado como é publicado no leads_exchange
+            attempts INTEGER DEFAULT 0,
+            last_error TEXT,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS seen_place (
+            place_id TEXT PRIMARY KEY,
+            search_progress_id INTEGER, -- última busca que publicou o lugar
+            first_seen_at TIMESTAMP,
+            last_fetched_at TIMESTAMP,
+            FOREIGN KEY(search_progress_id) REFERENCES search_progress(id)
+        );
+        CREATE TABLE IF NOT EXISTS search_schedule (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            categoria_id INTEGER,
+            state_id INTEGER,
+            city_id INTEGER,
+            district_id INTEGER,
+            zipcode_id INTEGER,
+            mode TEXT DEFAULT 'radius',
+            radius INTEGER,
+            max_results INTEGER DEFAULT 0,
+            max_pages INTEGER DEFAULT 3,
+            cron_expr TEXT NOT NULL,
+            enabled INTEGER DEFAULT 1,
+            last_run_at TIMESTAMP,
+            next_run_at TIMESTAMP,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY(categoria_id) REFERENCES categoria(id)
+        );
         
         
 	`
@@ -351,45 +695,106 @@ func setupDatabase() (*sql.DB, error) {
 		}
 	}
 
+	err = migrateDatabase(db)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("Database setup completed")
 	return db, nil
 }
 
-func updateSearchProgress(db *sql.DB, progressID int, pagesFetched int, leadsExtracted int, searchDone bool) error {
-	searchDoneValue := 0
-	if searchDone {
-		searchDoneValue = 1
-	}
+// Colunas adicionadas depois da criação inicial das tabelas. Bancos já
+// existentes recebem as colunas que faltam na inicialização do serviço.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"search_progress", "radius", "INTEGER"},
+	{"search_progress", "status", "TEXT DEFAULT 'queued'"},
+	{"search_progress", "error_count", "INTEGER DEFAULT 0"},
+	{"search_progress", "last_error", "TEXT"},
+	{"query_progress", "updated_at", "TIMESTAMP"},
+	{"search_progress", "schedule_id", "INTEGER REFERENCES search_schedule(id)"},
+	{"search_progress", "mode", "TEXT"},
+	{"search_progress", "max_results", "INTEGER DEFAULT 0"},
+	{"search_progress", "max_pages", "INTEGER DEFAULT 0"},
+	{"search_progress", "places_skipped", "INTEGER DEFAULT 0"},
+}
 
-	query := `
-        UPDATE search_progress 
-        SET pages_fetched = ?, leads_extracted = ?, search_done = ? 
-        WHERE id = ?`
+var indexMigrations = []string{
+	// Mantém apenas o registro mais recente de cada consulta antes de criar o índice único
+	`DELETE FROM query_progress WHERE id NOT IN (SELECT MAX(id) FROM query_progress GROUP BY query, location, radius)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_query_progress_key ON query_progress(query, location, radius)`,
+}
 
-	_, err := db.Exec(query, pagesFetched, leadsExtracted, searchDoneValue, progressID)
-	if err != nil {
-		return fmt.Errorf("Failed to update search progress: %v", err)
+func migrateDatabase(db *sql.DB) error {
+	for _, migration := range columnMigrations {
+		exists, err := columnExists(db, migration.table, migration.column)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %v", migration.table, err)
+		}
+		if exists {
+			continue
+		}
+
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", migration.table, migration.column, migration.definition)
+		_, err = db.Exec(stmt)
+		if err != nil {
+			return fmt.Errorf("failed to execute statement '%s': %v", stmt, err)
+		}
+		log.Printf("Coluna %s adicionada na tabela %s", migration.column, migration.table)
+
+		if migration.table == "search_progress" && migration.column == "status" {
+			_, err = db.Exec("UPDATE search_progress SET status = ? WHERE search_done = 1", repository.SearchStatusDone)
+			if err != nil {
+				return fmt.Errorf("failed to backfill search_progress status: %v", err)
+			}
+		}
 	}
 
-	log.Printf("Search progress updated: %d pages, %d leads extracted, done: %v", pagesFetched, leadsExtracted, searchDone)
+	for _, stmt := range indexMigrations {
+		_, err := db.Exec(stmt)
+		if err != nil {
+			return fmt.Errorf("failed to execute statement '%s': %v", stmt, err)
+		}
+	}
 	return nil
 }
 
-func startSearch(categoryID string, zipcodeID int, radius int, maxResults int, db *sql.DB, ch *amqp.Channel) error {
+func columnExists(db *sql.DB, table string, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func startSearch(ctx context.Context, job searchJob, db *sql.DB, provider googleplaces.PlacesProvider, publisher leadPublisher) error {
+	categoryID := job.CategoryID
+	zipcodeID := job.ZipcodeID
+	radius := job.Radius
+	maxResults := job.MaxResults
 
-	log.Printf("Iniciando pesquisa com categoryID: %s, zipcodeID: %d, radius: %d, maxResults: %d", categoryID, zipcodeID, radius, maxResults)
 	startTime := time.Now()
-	totalLeadsExtracted := 0
 	startSearchRequests.WithLabelValues(categoryID, "started").Inc()
-	log.Printf("Iniciando pesquisa com categoryID: %s, zipcodeID: %d, radius: %d", categoryID, zipcodeID, radius, maxResults)
+	log.Printf("Iniciando pesquisa %d com categoryID: %s, zipcodeID: %d, radius: %d, maxResults: %d", job.ProgressID, categoryID, zipcodeID, radius, maxResults)
 	totalRequests.WithLabelValues("startSearch", "internal").Inc()
 
-	apiKey := os.Getenv("GOOGLE_PLACES_API_KEY")
-	if apiKey == "" {
-		startSearchErrors.WithLabelValues(categoryID, "api_key_missing").Inc()
-		return fmt.Errorf("API key is required. Set the GOOGLE_PLACES_API_KEY environment variable.")
-	}
-
 	log.Println("Buscando nome da categoria no banco de dados...")
 	categoryName, err := repository.GetCategoryNameByID(db, categoryID)
 	if err != nil {
@@ -399,161 +804,285 @@ func startSearch(categoryID string, zipcodeID int, radius int, maxResults int, d
 	}
 	log.Printf("Categoria encontrada: %s", categoryName)
 
-	log.Println("Buscando informações de localização pelo zipcode ID...")
-	locationInfo, err := repository.GetLocationInfoByZipcodeID(db, zipcodeID)
+	log.Println("Buscando informações de localização...")
+	locationInfo, err := resolveSearchLocation(db, job)
 	if err != nil {
 		startSearchErrors.WithLabelValues(categoryID, "get_location_info").Inc()
-		log.Printf("Erro ao buscar informações de localização para zipcode ID %d: %v", zipcodeID, err)
-		return fmt.Errorf("Failed to get location info by zipcode: %v", err)
+		log.Printf("Erro ao buscar informações de localização da busca %d: %v", job.ProgressID, err)
+		return fmt.Errorf("Failed to get location info: %v", err)
 	}
 	log.Printf("Informações de localização encontradas: %+v", locationInfo)
+	cityName := locationInfo.CityName
 
-	log.Println("Buscando o primeiro CEP no intervalo...")
-	startZip, err := repository.GetFirstZipCodeInRange(db, zipcodeID)
-	if err != nil {
-		log.Printf("Erro ao buscar o primeiro CEP no intervalo para zipcode ID %d: %v", zipcodeID, err)
-		return fmt.Errorf("Failed to get first zip code in range: %v", err)
-	}
-	log.Printf("Primeiro CEP encontrado: %s", startZip)
+	service := googleplaces.NewServiceWithProvider(provider)
+	service.PageTokenDelay = getEnvDuration("PAGE_TOKEN_DELAY", service.PageTokenDelay)
+	service.ProgressStore = repository.NewQueryProgressStore(db)
+	service.GeocodeCache = repository.NewGeocodeCacheStore(db)
+	service.GeocodeCacheTTL = getEnvDuration("GEOCODE_CACHE_TTL", defaultGeocodeCacheTTL)
 
-	log.Println("Inserindo progresso inicial da busca no banco de dados...")
-	progress := repository.SearchProgress{
-		CategoriaID: categoryID,
-		CountryID:   locationInfo.CountryID,
-		StateID:     locationInfo.StateID,
-		CityID:      locationInfo.CityID,
-		DistrictID:  locationInfo.DistrictID,
-		ZipcodeID:   locationInfo.ZipcodeID,
-		Radius:      radius,
-		SearchDone:  0,
+	var placeDetailsFromSearch []map[string]interface{}
+	if job.Mode == searchModeSweep {
+		placeDetailsFromSearch, err = sweepPlaces(ctx, job, categoryName, locationInfo, service, db)
+	} else {
+		placeDetailsFromSearch, err = searchPlacesByZip(ctx, job, categoryName, service, db)
 	}
-
-	progressID, err := repository.InsertSearchProgress(db, progress)
-	if err != nil {
-		log.Printf("Erro ao inserir progresso inicial da busca: %v", err)
-		return fmt.Errorf("Failed to insert search progress: %v", err)
+	if err != nil && ctx.Err() != nil {
+		// Os lugares das páginas já lidas não voltam na busca retomada, que
+		// continua do próximo next_page_token
+		if searchInterrupted(ctx) {
+			parkPlacesForResume(db, job.ProgressID, placeIDsOf(placeDetailsFromSearch))
+		}
+		return err
 	}
-	log.Printf("Progresso inicial inserido com ID: %d", progressID)
-
-	log.Println("Obtendo o nome da cidade pelo ID...")
-	cityName, err := repository.GetCityNameByID(db, locationInfo.CityID)
-	if err != nil {
-		log.Printf("Erro ao obter o nome da cidade para city ID %d: %v", locationInfo.CityID, err)
-		return fmt.Errorf("Failed to get city name: %v", err)
+	// Uma falha no meio da busca não descarta os lugares já encontrados: eles
+	// seguem para os detalhes e a busca termina como falha no final
+	searchErr := err
+	if searchErr != nil && (len(placeDetailsFromSearch) == 0 || errors.Is(searchErr, googleplaces.ErrBudgetExceeded)) {
+		return searchErr
+	}
+	if searchErr != nil {
+		log.Printf("Busca %d: seguindo com os %d lugares encontrados antes do erro", job.ProgressID, len(placeDetailsFromSearch))
 	}
-	log.Printf("Nome da cidade: %s", cityName)
 
-	log.Println("Geocodificando o CEP inicial...")
-	geoStartTime := time.Now()
-	service := googleplaces.NewService(apiKey)
-	coordinates, err := service.GeocodeZip(startZip)
-	if err != nil {
-		startSearchErrors.WithLabelValues(categoryID, "geocode_zip").Inc()
-		log.Printf("Erro ao geocodificar o CEP %s: %v", startZip, err)
-		return fmt.Errorf("Failed to get coordinates for zip code: %v", err)
+	// Lugares já publicados não são consultados de novo; o corte em
+	// maxResults vem depois para que a busca traga lugares novos
+	searchResults := searchResultsByPlaceID(placeDetailsFromSearch)
+	placeIDs, skipped := filterSeenPlaces(db, placeIDsOf(placeDetailsFromSearch), getEnvDuration("PLACE_REFRESH_AFTER", defaultPlaceRefreshAfter), time.Now())
+	if skipped > 0 {
+		log.Printf("Busca %d: %d lugares já conhecidos não serão consultados", job.ProgressID, skipped)
+		placesSkipped.WithLabelValues(categoryID).Add(float64(skipped))
+		if err := repository.AddSearchProgressSkipped(db, job.ProgressID, skipped); err != nil {
+			log.Printf("Erro ao atualizar o progresso da busca %d: %v", job.ProgressID, err)
+		}
+	}
+	if maxResults > 0 && len(placeIDs) > maxResults {
+		placeIDs = placeIDs[:maxResults]
 	}
-	geocodingDuration.Observe(time.Since(geoStartTime).Seconds())
-	log.Printf("Coordenadas encontradas: %s", coordinates)
 
-	log.Println("Iniciando busca no Google Places...")
-	totalLeads := 0
-	maxPages := 1
-	totalLeadsExtracted = 0
-	for currentPage := 1; currentPage <= maxPages; currentPage++ {
-		log.Printf("Buscando página %d para a categoria %s na cidade %s", currentPage, categoryName, cityName)
+	// Cancelar o contexto ao sair também libera os workers quando a busca
+	// termina antes de consumir todos os resultados
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		placeDetailsFromSearch, err := service.SearchPlaces(categoryName, coordinates, radius, maxPages)
-		if err != nil {
-			log.Printf("Erro ao buscar lugares: %v", err)
-			return fmt.Errorf("Error fetching places: %v", err)
-		}
+	totalLeadsExtracted := 0
+	processed := 0
 
-		for _, place := range placeDetailsFromSearch {
-			placeID := place["PlaceID"].(string)
-			placeDetails, err := service.GetPlaceDetails(placeID)
-			if err != nil {
-				startSearchErrors.WithLabelValues(categoryID, "search_places").Inc()
-				log.Printf("Erro ao obter detalhes para o place ID %s: %v", placeID, err)
-				continue
+	for result := range fetchPlaceDetails(ctx, service, placeIDs, getEnvInt("DETAILS_CONCURRENCY", 4)) {
+		// Um resultado que chega depois do cancelamento provavelmente é o
+		// erro da própria chamada cancelada
+		if ctx.Err() != nil {
+			break
+		}
+		processed++
+		placeID := result.PlaceID
+		placeDetails, err := result.Details, result.Err
+		if errors.Is(err, googleplaces.ErrBudgetExceeded) {
+			startSearchErrors.WithLabelValues(categoryID, "budget_exceeded").Inc()
+			return err
+		}
+		if err != nil {
+			startSearchErrors.WithLabelValues(categoryID, "place_details").Inc()
+			log.Printf("Erro ao obter detalhes para o place ID %s: %v", placeID, err)
+			repository.RecordSearchError(db, job.ProgressID, fmt.Sprintf("place %s: %v", placeID, err))
+			if googleplaces.IsRetryable(err) {
+				parkPlaceForRetry(db, job.ProgressID, placeID, err)
 			}
+			continue
+		}
 
-			log.Printf("Detalhes do lugar obtidos: %+v", placeDetails)
+		log.Printf("Detalhes do lugar obtidos: %+v", placeDetails)
 
-			placeDetails["Category"] = categoryName
-			placeDetails["City"] = cityName
-			placeDetails["Radius"] = radius
+		lead := newLeadMessage(placeDetails, searchResults[placeID], categoryName, cityName, radius)
+		err = publisher.Publish(lead)
+		if err != nil {
+			log.Printf("Erro ao publicar lead no RabbitMQ: %v", err)
+			repository.RecordSearchError(db, job.ProgressID, fmt.Sprintf("publish %s: %v", placeID, err))
+			continue
+		}
 
-			err = publishLeadToRabbitMQ(ch, placeDetails)
-			if err != nil {
-				log.Printf("Erro ao publicar lead no RabbitMQ: %v", err)
-			}
+		totalLeadsExtracted++
+		leadsExtracted.WithLabelValues(categoryID).Inc()
+		markPlaceFetched(db, placeID, job.ProgressID)
 
-			totalLeadsExtracted++
+		// Incrementa em vez de gravar o total: a busca pode ser a retomada de
+		// uma execução anterior e o reprocessamento também soma leads
+		err = repository.IncrementSearchProgressLeads(db, job.ProgressID)
+		if err != nil {
+			log.Printf("Erro ao atualizar o progresso da busca %d: %v", job.ProgressID, err)
 		}
-
-		if totalLeadsExtracted >= maxResults {
-			log.Printf("Limite de resultados atingido após processar a página: %d", maxResults)
-			break
+	}
+	if err := ctx.Err(); err != nil {
+		log.Printf("Busca %d interrompida com %d leads publicados", job.ProgressID, totalLeadsExtracted)
+		if searchInterrupted(ctx) {
+			parkPlacesForResume(db, job.ProgressID, placeIDs[processed:])
 		}
+		return err
+	}
 
-		log.Printf("Progresso da busca: página %d completada, %d leads extraídos", currentPage, totalLeadsExtracted)
+	if searchErr != nil {
+		log.Printf("Busca %d terminou com %d leads publicados depois de um erro na busca", job.ProgressID, totalLeadsExtracted)
+		return searchErr
 	}
 
 	duration := time.Since(startTime).Seconds()
 	startSearchDuration.WithLabelValues(categoryID).Observe(duration)
 	startSearchRequests.WithLabelValues(categoryID, "completed").Inc()
-	log.Printf("Busca concluída com sucesso! Total de leads: %d", totalLeads)
+	log.Printf("Busca concluída com sucesso! Total de leads: %d", totalLeadsExtracted)
 
-	log.Println("Busca concluída com sucesso!")
 	return nil
 }
 
-func getFirstZipCodeInRange(db *sql.DB, districtID int) (string, error) {
-	var startZip string
-	err := db.QueryRow("SELECT start_zip FROM zipcode WHERE district_id = ?", districtID).Scan(&startZip)
+// Busca em um único raio em volta do primeiro CEP do intervalo
+func searchPlacesByZip(ctx context.Context, job searchJob, categoryName string, service *googleplaces.Service, db *sql.DB) ([]map[string]interface{}, error) {
+	log.Println("Buscando o primeiro CEP no intervalo...")
+	startZip, _, err := repository.GetZipRangeByID(db, job.ZipcodeID)
 	if err != nil {
-		return "", err
+		log.Printf("Erro ao buscar o primeiro CEP no intervalo para zipcode ID %d: %v", job.ZipcodeID, err)
+		return nil, fmt.Errorf("Failed to get first zip code in range: %v", err)
 	}
-	return startZip, nil
+	log.Printf("Primeiro CEP encontrado: %s", startZip)
+
+	log.Println("Geocodificando o CEP inicial...")
+	geoStartTime := time.Now()
+	coordinates, cached, err := service.GeocodeZip(ctx, startZip)
+	if err != nil {
+		startSearchErrors.WithLabelValues(job.CategoryID, "geocode_zip").Inc()
+		log.Printf("Erro ao geocodificar o CEP %s: %v", startZip, err)
+		return nil, fmt.Errorf("Failed to get coordinates for zip code: %v", err)
+	}
+	geocodingDuration.WithLabelValues(cacheLabel(cached)).Observe(time.Since(geoStartTime).Seconds())
+	log.Printf("Coordenadas encontradas: %s", coordinates)
+
+	log.Printf("Iniciando busca no Google Places para a categoria %s...", categoryName)
+	places, err := service.SearchPlaces(ctx, categoryName, coordinates, job.Radius, job.MaxPages, job.MaxResults, func(page int, results int) {
+		log.Printf("Busca %d: página %d com %d resultados", job.ProgressID, page, results)
+		if err := repository.UpdateSearchProgressPage(db, job.ProgressID, page); err != nil {
+			log.Printf("Erro ao atualizar as páginas da busca %d: %v", job.ProgressID, err)
+		}
+	})
+	if err != nil {
+		startSearchErrors.WithLabelValues(job.CategoryID, "search_places").Inc()
+		log.Printf("Erro ao buscar lugares: %v", err)
+		return places, fmt.Errorf("Error fetching places: %w", err)
+	}
+	return places, nil
 }
 
-func publishLeadToRabbitMQ(ch *amqp.Channel, leadData map[string]interface{}) error {
-	exchangeName := "leads_exchange"
-
-	err := ch.ExchangeDeclare(
-		exchangeName, // nome do exchange
-		"fanout",     // tipo
-		true,         // durável
-		false,        // auto-deletar
-		false,        // interno
-		false,        // sem espera
-		nil,          // argumentos
-	)
+// Varre toda a área do alvo (cidade, bairro ou CEP) com uma grade de círculos
+func sweepPlaces(ctx context.Context, job searchJob, categoryName string, locationInfo *repository.LocationInfo, service *googleplaces.Service, db *sql.DB) ([]map[string]interface{}, error) {
+	address, err := sweepAddress(db, job, locationInfo)
 	if err != nil {
-		return fmt.Errorf("Failed to declare exchange: %v", err)
+		return nil, err
 	}
 
-	// Serialize lead data
-	body, err := json.Marshal(leadData)
+	geoStartTime := time.Now()
+	bounds, err := service.GeocodeBounds(ctx, address)
 	if err != nil {
-		return fmt.Errorf("Failed to serialize lead data: %v", err)
-	}
-
-	// Publish the message
-	err = ch.Publish(
-		exchangeName,
-		"",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+		startSearchErrors.WithLabelValues(job.CategoryID, "geocode_bounds").Inc()
+		log.Printf("Erro ao geocodificar a área %s: %v", address, err)
+		return nil, fmt.Errorf("Failed to get bounds for %s: %v", address, err)
+	}
+	geocodingDuration.WithLabelValues(cacheLabel(false)).Observe(time.Since(geoStartTime).Seconds())
+	log.Printf("Área encontrada para %s: %+v", address, bounds)
+
+	pagesFetched := 0
+	cellsSearched := 0
+	places, err := service.Sweep(ctx, categoryName, bounds, googleplaces.SweepOptions{
+		Radius:    job.Radius,
+		MinRadius: getEnvInt("SWEEP_MIN_RADIUS", 250),
+		MaxCells:  getEnvInt("SWEEP_MAX_CELLS", 2000),
+		OnPage: func(page int, results int) {
+			pagesFetched++
+			if err := repository.UpdateSearchProgressPage(db, job.ProgressID, pagesFetched); err != nil {
+				log.Printf("Erro ao atualizar as páginas da busca %d: %v", job.ProgressID, err)
+			}
 		},
-	)
+		OnCell: func(cell googleplaces.SweepCell, results int, newPlaces int) {
+			cellsSearched++
+			log.Printf("Busca %d: célula %d (%s, raio %dm) com %d resultados, %d novos", job.ProgressID, cellsSearched, cell.Center, cell.Radius, results, newPlaces)
+		},
+		OnCellError: func(cell googleplaces.SweepCell, err error) {
+			startSearchErrors.WithLabelValues(job.CategoryID, "sweep_cell").Inc()
+			if err := repository.RecordSearchError(db, job.ProgressID, err.Error()); err != nil {
+				log.Printf("Erro ao registrar falha da busca %d: %v", job.ProgressID, err)
+			}
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("Failed to publish a message: %v", err)
+		startSearchErrors.WithLabelValues(job.CategoryID, "sweep").Inc()
+		log.Printf("Erro na varredura da busca %d: %v", job.ProgressID, err)
+		return places, fmt.Errorf("Error sweeping area: %w", err)
+	}
+	return places, nil
+}
+
+func sweepAddress(db *sql.DB, job searchJob, locationInfo *repository.LocationInfo) (string, error) {
+	if job.ZipcodeID != 0 {
+		startZip, _, err := repository.GetZipRangeByID(db, job.ZipcodeID)
+		if err != nil {
+			return "", fmt.Errorf("Failed to get zip range: %v", err)
+		}
+		return startZip, nil
 	}
 
-	log.Printf("Lead published to RabbitMQ: %s", body)
-	return nil
+	parts := []string{locationInfo.CityName, locationInfo.StateName, locationInfo.CountryName}
+	if job.DistrictID != 0 {
+		parts = append([]string{locationInfo.DistrictName}, parts...)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// Resolve a hierarquia de localização a partir do alvo mais específico do job
+func resolveSearchLocation(db *sql.DB, job searchJob) (*repository.LocationInfo, error) {
+	switch {
+	case job.ZipcodeID != 0:
+		return repository.GetLocationInfoByZipcodeID(db, job.ZipcodeID)
+	case job.DistrictID != 0:
+		return repository.GetLocationInfoByDistrictID(db, job.DistrictID)
+	case job.CityID != 0:
+		return repository.GetLocationInfoByCityID(db, job.CityID)
+	}
+	return nil, fmt.Errorf("search %d has no location target", job.ProgressID)
+}
+
+func getFirstZipCodeInRange(db *sql.DB, districtID int) (string, error) {
+	var startZip string
+	err := db.QueryRow("SELECT start_zip FROM zipcode WHERE district_id = ?", districtID).Scan(&startZip)
+	if err != nil {
+		return "", err
+	}
+	return startZip, nil
+}
+
+// Escolhe a fonte dos lugares pelo PLACES_PROVIDER: "google" (padrão) chama a
+// API, "fixture" reproduz as respostas gravadas em PLACES_FIXTURES_DIR
+func newPlacesProvider(apiKey string) (googleplaces.PlacesProvider, error) {
+	switch os.Getenv("PLACES_PROVIDER") {
+	case "", "google":
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key is required. Set the GOOGLE_PLACES_API_KEY environment variable.")
+		}
+		client := googleplaces.NewHTTPClient()
+		provider := googleplaces.NewGoogleProvider(apiKey, os.Getenv("GOOGLE_MAPS_BASE_URL"), client)
+		provider.RecordDir = os.Getenv("PLACES_RECORD_DIR")
+
+		// PLACES_API_VERSION=v1 usa a Places API (New) com máscaras de campos;
+		// a geocodificação continua na Geocoding API
+		switch os.Getenv("PLACES_API_VERSION") {
+		case "", "legacy":
+			return provider, nil
+		case "v1":
+			log.Println("Usando a Places API (New)")
+			return googleplaces.NewPlacesV1Provider(apiKey, os.Getenv("PLACES_API_BASE_URL"), client, provider), nil
+		}
+		return nil, fmt.Errorf("unknown PLACES_API_VERSION: %s", os.Getenv("PLACES_API_VERSION"))
+	case "fixture":
+		dir := os.Getenv("PLACES_FIXTURES_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("PLACES_FIXTURES_DIR is required when PLACES_PROVIDER=fixture")
+		}
+		log.Printf("Usando respostas gravadas em %s no lugar da API do Google", dir)
+		return googleplaces.NewFixtureProvider(dir), nil
+	}
+	return nil, fmt.Errorf("unknown PLACES_PROVIDER: %s", os.Getenv("PLACES_PROVIDER"))
 }
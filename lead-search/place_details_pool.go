@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"lead-search/googleplaces"
+)
+
+type placeDetailsResult struct {
+	PlaceID string
+	Details map[string]interface{}
+	Err     error
+}
+
+// Busca os detalhes com até concurrency chamadas simultâneas e entrega os
+// resultados na ordem de placeIDs. O limitador de API continua valendo porque
+// cada worker passa pelo mesmo provider. Quando ctx é cancelado os workers
+// param de pegar novos lugares e o canal é fechado.
+func fetchPlaceDetails(ctx context.Context, service *googleplaces.Service, placeIDs []string, concurrency int) <-chan placeDetailsResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Um canal por lugar, com buffer, para que os workers nunca fiquem
+	// bloqueados esperando a publicação dos lugares anteriores
+	slots := make([]chan placeDetailsResult, len(placeIDs))
+	for i := range slots {
+		slots[i] = make(chan placeDetailsResult, 1)
+	}
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range placeIDs {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range indexes {
+				details, err := service.GetPlaceDetails(ctx, placeIDs[i])
+				slots[i] <- placeDetailsResult{PlaceID: placeIDs[i], Details: details, Err: err}
+			}
+		}()
+	}
+
+	results := make(chan placeDetailsResult)
+	go func() {
+		defer close(results)
+		for _, slot := range slots {
+			select {
+			case result := <-slot:
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+		workers.Wait()
+	}()
+	return results
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+)
+
+const (
+	placeRetryBaseDelay = 5 * time.Minute
+	placeRetryMaxDelay  = 24 * time.Hour
+	placeRetryBatchSize = 100
+)
+
+// Espera antes da próxima tentativa de um place ID guardado: dobra a cada
+// falha, de 5 minutos até no máximo um dia
+func placeRetryDelay(attempts int) time.Duration {
+	delay := placeRetryBaseDelay << uint(attempts-1)
+	if delay <= 0 || delay > placeRetryMaxDelay {
+		return placeRetryMaxDelay
+	}
+	return delay
+}
+
+// Guarda o place ID para reprocessamento quando os detalhes falharam por erro
+// temporário mesmo depois das retentativas imediatas
+func parkPlaceForRetry(db *sql.DB, progressID int64, placeID string, err error) {
+	parkErr := repository.ParkPlaceRetry(db, progressID, placeID, err.Error(), time.Now().Add(placeRetryDelay(1)))
+	if parkErr != nil {
+		log.Printf("Erro ao guardar o place ID %s para nova tentativa: %v", placeID, parkErr)
+		return
+	}
+	log.Printf("Place ID %s guardado para nova tentativa", placeID)
+}
+
+// Reprocessa periodicamente os place IDs guardados em place_retry
+type placeRetryWorker struct {
+	db          *sql.DB
+	provider    googleplaces.PlacesProvider
+	limiter     *googleplaces.Limiter
+	publisher   leadPublisher
+	maxAttempts int
+}
+
+// Roda até o ctx ser cancelado; uma rodada em andamento para no próximo place ID
+func (w *placeRetryWorker) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.runOnce(ctx, now)
+			}
+		}
+	}()
+	log.Printf("Reprocessamento de place IDs iniciado a cada %s", interval)
+}
+
+func (w *placeRetryWorker) runOnce(ctx context.Context, now time.Time) {
+	retries, err := repository.ListDuePlaceRetries(w.db, now, placeRetryBatchSize)
+	if err != nil {
+		log.Printf("Erro ao carregar os place IDs para nova tentativa: %v", err)
+		return
+	}
+
+	for _, retry := range retries {
+		if ctx.Err() != nil {
+			return
+		}
+		retryable, err := w.retry(ctx, retry)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			if err := repository.MarkPlaceRetryDone(w.db, retry.ID); err != nil {
+				log.Printf("%v", err)
+			}
+			continue
+		}
+
+		attempts := retry.Attempts + 1
+		giveUp := !retryable || attempts >= w.maxAttempts
+		log.Printf("Nova tentativa do place ID %s falhou (%d/%d): %v", retry.PlaceID, attempts, w.maxAttempts, err)
+		if err := repository.ReschedulePlaceRetry(w.db, retry.ID, err.Error(), now.Add(placeRetryDelay(attempts)), giveUp); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// O bool indica se uma falha ainda pode dar certo numa próxima tentativa
+func (w *placeRetryWorker) retry(ctx context.Context, retry repository.PlaceRetry) (bool, error) {
+	progress, err := repository.GetSearchProgressByID(w.db, retry.SearchProgressID)
+	if err != nil {
+		return false, err
+	}
+
+	categoryName, err := repository.GetCategoryNameByID(w.db, progress.CategoriaID)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get category name: %v", err)
+	}
+
+	locationInfo, err := resolveSearchLocation(w.db, searchJobFromProgress(progress))
+	if err != nil {
+		return false, fmt.Errorf("Failed to get location info: %v", err)
+	}
+
+	provider := googleplaces.NewRetryingProvider(
+		meteredProviderFor(w.db, w.provider, w.limiter, progress.ID),
+		googleplaces.DefaultRetryPolicy,
+	)
+	placeDetails, err := provider.Details(ctx, retry.PlaceID)
+	if err != nil {
+		return googleplaces.IsRetryable(err), err
+	}
+
+	lead := newLeadMessage(placeDetails, nil, categoryName, locationInfo.CityName, progress.Radius)
+	if err := lead.Validate(); err != nil {
+		return false, err
+	}
+	err = w.publisher.Publish(lead)
+	if err != nil {
+		return true, fmt.Errorf("publish %s: %v", retry.PlaceID, err)
+	}
+
+	leadsExtracted.WithLabelValues(progress.CategoriaID).Inc()
+	markPlaceFetched(w.db, retry.PlaceID, progress.ID)
+	if err := repository.IncrementSearchProgressLeads(w.db, progress.ID); err != nil {
+		log.Printf("Erro ao atualizar o progresso da busca %d: %v", progress.ID, err)
+	}
+	log.Printf("Place ID %s reprocessado com sucesso", retry.PlaceID)
+	return false, nil
+}
+
+// Reconstrói o alvo de uma busca já gravada em search_progress
+func searchJobFromProgress(progress *repository.SearchProgress) searchJob {
+	job := searchJob{
+		ProgressID: progress.ID,
+		ScheduleID: progress.ScheduleID,
+		CategoryID: progress.CategoriaID,
+		Mode:       progress.Mode,
+		Radius:     progress.Radius,
+		MaxResults: progress.MaxResults,
+		MaxPages:   progress.MaxPages,
+	}
+	if job.Mode == "" {
+		job.Mode = searchModeRadius
+	}
+	job.ZipcodeID, _ = strconv.Atoi(progress.ZipcodeID)
+	job.DistrictID, _ = strconv.Atoi(progress.DistrictID)
+	job.CityID, _ = strconv.Atoi(progress.CityID)
+	return job
+}
+
+// Guarda os lugares que uma busca interrompida já tinha encontrado mas não
+// chegou a publicar. Eles ficam prontos para o reprocessamento assim que o
+// serviço subir de novo.
+func parkPlacesForResume(db *sql.DB, progressID int64, placeIDs []string) {
+	for _, placeID := range placeIDs {
+		err := repository.ParkPlaceRetry(db, progressID, placeID, "search interrupted", time.Now())
+		if err != nil {
+			log.Printf("Erro ao guardar o place ID %s da busca interrompida: %v", placeID, err)
+		}
+	}
+	if len(placeIDs) > 0 {
+		log.Printf("%d place IDs da busca %d guardados para reprocessamento", len(placeIDs), progressID)
+	}
+}
+
+func placeIDsOf(places []map[string]interface{}) []string {
+	placeIDs := make([]string, 0, len(places))
+	for _, place := range places {
+		if placeID, _ := place["PlaceID"].(string); placeID != "" {
+			placeIDs = append(placeIDs, placeID)
+		}
+	}
+	return placeIDs
+}
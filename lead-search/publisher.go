@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"shared/leadmessage"
+
+	"github.com/streadway/amqp"
+)
+
+const leadsExchange = "leads_exchange"
+
+type leadPublisher interface {
+	Publish(lead leadmessage.LeadMessage) error
+}
+
+// Publica no RabbitMQ com publisher confirms. A conexão é aberta sob demanda
+// e reaberta na próxima publicação depois de qualquer falha, então um
+// restart do RabbitMQ não exige reiniciar o serviço.
+type rabbitPublisher struct {
+	url            string
+	confirmTimeout time.Duration
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+func newRabbitPublisher(url string, confirmTimeout time.Duration) *rabbitPublisher {
+	return &rabbitPublisher{url: url, confirmTimeout: confirmTimeout}
+}
+
+func rabbitMQURL() (string, error) {
+	rabbitmqHost := os.Getenv("RABBITMQ_HOST")
+	rabbitmqPort := os.Getenv("RABBITMQ_PORT")
+	if rabbitmqHost == "" || rabbitmqPort == "" {
+		return "", fmt.Errorf("RABBITMQ_HOST and RABBITMQ_PORT must be set")
+	}
+	return fmt.Sprintf("amqp://guest:guest@%s:%s/", rabbitmqHost, rabbitmqPort), nil
+}
+
+// Tenta conectar algumas vezes na subida. Se não conseguir o serviço sobe
+// mesmo assim: os leads ficam no outbox até o RabbitMQ voltar.
+func (p *rabbitPublisher) Connect(attempts int, delay time.Duration) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		p.mu.Lock()
+		err = p.ensureConnected()
+		p.mu.Unlock()
+		if err == nil {
+			log.Println("Conectado ao RabbitMQ")
+			return nil
+		}
+
+		log.Printf("Falha ao conectar ao RabbitMQ, tentando novamente em %s (%d/%d): %v", delay, i+1, attempts, err)
+		time.Sleep(delay)
+	}
+	return err
+}
+
+func (p *rabbitPublisher) Publish(lead leadmessage.LeadMessage) error {
+	body, err := leadmessage.Encode(lead)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize lead data: %v", err)
+	}
+	return p.PublishBody(body)
+}
+
+// Publica e espera o broker confirmar. Publicações são serializadas para que
+// cada confirmação corresponda à mensagem que acabou de sair. A versão do
+// schema vai no header para o consumidor decidir antes de ler o corpo.
+func (p *rabbitPublisher) PublishBody(body []byte) error {
+	version, err := leadmessage.PeekVersion(body)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+
+	err = p.ch.Publish(leadsExchange, "", false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Headers:      amqp.Table{leadmessage.VersionHeader: int32(version)},
+		Body:         body,
+	})
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("Failed to publish a message: %v", err)
+	}
+
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			p.reset()
+			return fmt.Errorf("RabbitMQ channel closed before confirming the message")
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("RabbitMQ rejected the message (delivery tag %d)", confirm.DeliveryTag)
+		}
+	case <-time.After(p.confirmTimeout):
+		// Uma confirmação atrasada chegaria para a próxima mensagem
+		p.reset()
+		return fmt.Errorf("timed out waiting for RabbitMQ confirmation after %s", p.confirmTimeout)
+	}
+
+	log.Printf("Lead published to RabbitMQ: %s", body)
+	return nil
+}
+
+func (p *rabbitPublisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+}
+
+// Chamado com mu travado
+func (p *rabbitPublisher) ensureConnected() error {
+	if p.conn != nil && !p.conn.IsClosed() {
+		return nil
+	}
+	p.reset()
+
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open RabbitMQ channel: %v", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %v", err)
+	}
+
+	// O exchange é declarado uma vez por conexão, não a cada mensagem
+	err = ch.ExchangeDeclare(
+		leadsExchange, // nome do exchange
+		"fanout",      // tipo
+		true,          // durável
+		false,         // auto-deletar
+		false,         // interno
+		false,         // sem espera
+		nil,           // argumentos
+	)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Failed to declare exchange: %v", err)
+	}
+
+	p.conn = conn
+	p.ch = ch
+	p.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// Descarta a conexão atual; a próxima publicação abre outra
+func (p *rabbitPublisher) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn = nil
+	p.ch = nil
+	p.confirms = nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Chamadas e custo estimado de um SKU do Google para uma busca, agregados por dia (UTC)
+type APICost struct {
+	SKU           string
+	Calls         int
+	EstimatedCost float64
+}
+
+func RecordAPICall(db *sql.DB, searchProgressID int64, sku string, cost float64, at time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO api_cost_ledger (search_progress_id, sku, day, calls, estimated_cost)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT(search_progress_id, sku, day) DO UPDATE SET
+			calls = calls + 1,
+			estimated_cost = estimated_cost + excluded.estimated_cost,
+			updated_at = CURRENT_TIMESTAMP
+	`, searchProgressID, sku, at.UTC().Format("2006-01-02"), cost)
+	if err != nil {
+		return fmt.Errorf("failed to record API call: %v", err)
+	}
+	return nil
+}
+
+func GetSearchAPICosts(db *sql.DB, searchProgressID int64) ([]APICost, error) {
+	rows, err := db.Query(`
+		SELECT sku, SUM(calls), SUM(estimated_cost)
+		FROM api_cost_ledger
+		WHERE search_progress_id = ?
+		GROUP BY sku
+		ORDER BY sku
+	`, searchProgressID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API costs: %v", err)
+	}
+	defer rows.Close()
+
+	var costs []APICost
+	for rows.Next() {
+		var cost APICost
+		if err := rows.Scan(&cost.SKU, &cost.Calls, &cost.EstimatedCost); err != nil {
+			return nil, err
+		}
+		costs = append(costs, cost)
+	}
+	return costs, rows.Err()
+}
+
+// Gasto estimado de todas as buscas no dia (UTC) de at
+func GetAPISpendForDay(db *sql.DB, at time.Time) (float64, error) {
+	var spent sql.NullFloat64
+	err := db.QueryRow(`SELECT SUM(estimated_cost) FROM api_cost_ledger WHERE day = ?`, at.UTC().Format("2006-01-02")).Scan(&spent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load API spend: %v", err)
+	}
+	return spent.Float64, nil
+}
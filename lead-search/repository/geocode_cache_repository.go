@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Coordenadas de um CEP já geocodificado, chaveadas pelo CEP normalizado (só dígitos)
+type GeocodeCacheEntry struct {
+	ZipCode          string
+	Lat              float64
+	Lng              float64
+	FormattedAddress string
+	Source           string
+	UpdatedAt        time.Time
+}
+
+type GeocodeCacheStore interface {
+	LoadGeocode(zipCode string) (*GeocodeCacheEntry, error)
+	SaveGeocode(entry GeocodeCacheEntry) error
+}
+
+type SQLiteGeocodeCacheStore struct {
+	db *sql.DB
+}
+
+func NewGeocodeCacheStore(db *sql.DB) *SQLiteGeocodeCacheStore {
+	return &SQLiteGeocodeCacheStore{db: db}
+}
+
+// Retorna nil quando o CEP ainda não foi geocodificado
+func (s *SQLiteGeocodeCacheStore) LoadGeocode(zipCode string) (*GeocodeCacheEntry, error) {
+	entry := GeocodeCacheEntry{ZipCode: zipCode}
+	var formattedAddress, source sql.NullString
+	var updatedAt sql.NullTime
+
+	err := s.db.QueryRow(`
+		SELECT lat, lng, formatted_address, source, updated_at
+		FROM geocode_cache
+		WHERE zip_code = ?
+	`, zipCode).Scan(&entry.Lat, &entry.Lng, &formattedAddress, &source, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load geocode cache: %v", err)
+	}
+
+	entry.FormattedAddress = formattedAddress.String
+	entry.Source = source.String
+	entry.UpdatedAt = updatedAt.Time
+	return &entry, nil
+}
+
+func (s *SQLiteGeocodeCacheStore) SaveGeocode(entry GeocodeCacheEntry) error {
+	_, err := s.db.Exec(`
+		INSERT INTO geocode_cache (zip_code, lat, lng, formatted_address, source, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(zip_code) DO UPDATE SET
+			lat = excluded.lat,
+			lng = excluded.lng,
+			formatted_address = excluded.formatted_address,
+			source = excluded.source,
+			updated_at = excluded.updated_at
+	`, entry.ZipCode, entry.Lat, entry.Lng, entry.FormattedAddress, entry.Source)
+	if err != nil {
+		return fmt.Errorf("failed to save geocode cache: %v", err)
+	}
+	return nil
+}
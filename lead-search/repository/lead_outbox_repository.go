@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Lead já serializado esperando a confirmação do RabbitMQ
+type OutboxMessage struct {
+	ID        int64
+	PlaceID   string
+	Body      []byte
+	Attempts  int
+	LastError string
+}
+
+func InsertOutboxMessage(db *sql.DB, placeID string, body []byte) (int64, error) {
+	result, err := db.Exec(`INSERT INTO lead_outbox (place_id, body) VALUES (?, ?)`, nullIfEmpty(placeID), string(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert outbox message: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+func DeleteOutboxMessage(db *sql.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM lead_outbox WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox message %d: %v", id, err)
+	}
+	return nil
+}
+
+func RecordOutboxFailure(db *sql.DB, id int64, lastError string) error {
+	_, err := db.Exec(`UPDATE lead_outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox failure for message %d: %v", id, err)
+	}
+	return nil
+}
+
+// Mensagens pendentes na ordem em que foram gravadas
+func ListOutboxMessages(db *sql.DB, limit int) ([]OutboxMessage, error) {
+	rows, err := db.Query(`SELECT id, place_id, body, attempts, last_error FROM lead_outbox ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var message OutboxMessage
+		var placeID, lastError sql.NullString
+		var body string
+		if err := rows.Scan(&message.ID, &placeID, &body, &message.Attempts, &lastError); err != nil {
+			return nil, err
+		}
+		message.PlaceID = placeID.String
+		message.Body = []byte(body)
+		message.LastError = lastError.String
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func CountOutboxMessages(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM lead_outbox`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count outbox messages: %v", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Valores da coluna status de state, city, district, zipcode e categoria
+const (
+	LocationStatusPending    = 0
+	LocationStatusInProgress = 1
+	LocationStatusDone       = 2
+)
+
+// Lista os zipcodes abaixo do alvo mais específico informado (bairro, cidade ou estado)
+func ListZipcodeIDs(db *sql.DB, stateID int, cityID int, districtID int) ([]int, error) {
+	var query string
+	var arg int
+	switch {
+	case districtID != 0:
+		query = `SELECT z.id FROM zipcode z WHERE z.district_id = ? ORDER BY z.id`
+		arg = districtID
+	case cityID != 0:
+		query = `
+			SELECT z.id FROM zipcode z
+			JOIN district d ON z.district_id = d.id
+			WHERE d.city_id = ?
+			ORDER BY z.id`
+		arg = cityID
+	case stateID != 0:
+		query = `
+			SELECT z.id FROM zipcode z
+			JOIN district d ON z.district_id = d.id
+			JOIN city c ON d.city_id = c.id
+			WHERE c.state_id = ?
+			ORDER BY z.id`
+		arg = stateID
+	default:
+		return nil, fmt.Errorf("state_id, city_id or district_id is required")
+	}
+
+	rows, err := db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zipcodes: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Zipcodes que já têm uma busca concluída, na fila, em execução ou esperando
+// ser retomada para a categoria
+func GetScheduledZipcodeIDs(db *sql.DB, categoryID string) (map[int]bool, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT zipcode_id FROM search_progress
+		WHERE categoria_id = ? AND zipcode_id IS NOT NULL
+			AND (search_done = 1 OR status IN (?, ?, ?))
+	`, categoryID, SearchStatusQueued, SearchStatusRunning, SearchStatusInterrupted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list searched zipcodes: %v", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id sql.NullInt64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if id.Valid {
+			ids[int(id.Int64)] = true
+		}
+	}
+	return ids, rows.Err()
+}
+
+// Uma busca sp ainda está pendente se não terminou, não falhou nem foi
+// cancelada e nenhuma outra execução para a mesma categoria e o mesmo alvo
+// foi concluída
+const pendingSearchCondition = `sp.search_done = 0
+	AND COALESCE(sp.status, '') NOT IN ('` + SearchStatusFailed + `', '` + SearchStatusCancelled + `')
+	AND NOT EXISTS (
+	SELECT 1 FROM search_progress done
+	WHERE done.search_done = 1
+		AND done.categoria_id = sp.categoria_id
+		AND done.zipcode_id IS sp.zipcode_id
+		AND done.district_id IS sp.district_id
+		AND done.city_id IS sp.city_id)`
+
+// Marca o zipcode e toda a hierarquia acima dele como em andamento
+func MarkZipcodeInProgress(db *sql.DB, zipcodeID int) error {
+	statements := []string{
+		`UPDATE zipcode SET status = ? WHERE id = ?`,
+		`UPDATE district SET status = ? WHERE id = (SELECT district_id FROM zipcode WHERE id = ?)`,
+		`UPDATE city SET status = ? WHERE id = (
+			SELECT d.city_id FROM zipcode z JOIN district d ON z.district_id = d.id WHERE z.id = ?)`,
+		`UPDATE state SET status = ? WHERE id = (
+			SELECT c.state_id FROM zipcode z
+			JOIN district d ON z.district_id = d.id
+			JOIN city c ON d.city_id = c.id
+			WHERE z.id = ?)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt, LocationStatusInProgress, zipcodeID); err != nil {
+			return fmt.Errorf("failed to mark zipcode %d in progress: %v", zipcodeID, err)
+		}
+	}
+	return nil
+}
+
+func MarkCategoryInProgress(db *sql.DB, categoryID string) error {
+	_, err := db.Exec(`UPDATE categoria SET status = ? WHERE id = ?`, LocationStatusInProgress, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to mark category %s in progress: %v", categoryID, err)
+	}
+	return nil
+}
+
+// Recalcula o status do zipcode a partir das buscas em search_progress e
+// propaga para bairro, cidade e estado: um nível só fica concluído quando
+// todos os filhos estão concluídos. Sem busca pendente o zipcode só fica
+// concluído se alguma busca terminou; se todas falharam ou foram canceladas
+// ele volta a pendente.
+func RefreshZipcodeStatus(db *sql.DB, zipcodeID int) error {
+	_, err := db.Exec(`
+		UPDATE zipcode SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM search_progress sp WHERE sp.zipcode_id = zipcode.id AND `+pendingSearchCondition+`) THEN ?
+			WHEN EXISTS (SELECT 1 FROM search_progress sp WHERE sp.zipcode_id = zipcode.id AND sp.search_done = 1) THEN ?
+			ELSE ? END
+		WHERE id = ?
+	`, LocationStatusInProgress, LocationStatusDone, LocationStatusPending, zipcodeID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh status for zipcode %d: %v", zipcodeID, err)
+	}
+
+	parents := []string{
+		`UPDATE district SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM zipcode z WHERE z.district_id = district.id AND z.status != ?) THEN ?
+			ELSE ? END
+		WHERE id = (SELECT district_id FROM zipcode WHERE id = ?)`,
+		`UPDATE city SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM district d WHERE d.city_id = city.id AND d.status != ?) THEN ?
+			ELSE ? END
+		WHERE id = (SELECT d.city_id FROM zipcode z JOIN district d ON z.district_id = d.id WHERE z.id = ?)`,
+		`UPDATE state SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM city c WHERE c.state_id = state.id AND c.status != ?) THEN ?
+			ELSE ? END
+		WHERE id = (
+			SELECT c.state_id FROM zipcode z
+			JOIN district d ON z.district_id = d.id
+			JOIN city c ON d.city_id = c.id
+			WHERE z.id = ?)`,
+	}
+	for _, stmt := range parents {
+		if _, err := db.Exec(stmt, LocationStatusDone, LocationStatusInProgress, LocationStatusDone, zipcodeID); err != nil {
+			return fmt.Errorf("failed to refresh status for zipcode %d: %v", zipcodeID, err)
+		}
+	}
+	return nil
+}
+
+// Mesma regra do zipcode, para a categoria
+func RefreshCategoryStatus(db *sql.DB, categoryID string) error {
+	_, err := db.Exec(`
+		UPDATE categoria SET status = CASE
+			WHEN EXISTS (SELECT 1 FROM search_progress sp WHERE sp.categoria_id = categoria.id AND `+pendingSearchCondition+`) THEN ?
+			WHEN EXISTS (SELECT 1 FROM search_progress sp WHERE sp.categoria_id = categoria.id AND sp.search_done = 1) THEN ?
+			ELSE ? END
+		WHERE id = ?
+	`, LocationStatusInProgress, LocationStatusDone, LocationStatusPending, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to refresh status for category %s: %v", categoryID, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Valores da coluna status de place_retry
+const (
+	PlaceRetryPending = "pending"
+	PlaceRetryDone    = "done"
+	PlaceRetryFailed  = "failed"
+)
+
+// Place ID cujos detalhes não puderam ser obtidos por erro temporário da API.
+// Fica guardado para ser reprocessado depois, ligado à busca que o encontrou.
+type PlaceRetry struct {
+	ID               int64
+	SearchProgressID int64
+	PlaceID          string
+	Attempts         int
+	LastError        string
+	Status           string
+	NextAttemptAt    time.Time
+}
+
+func ParkPlaceRetry(db *sql.DB, searchProgressID int64, placeID string, lastError string, nextAttemptAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO place_retry (search_progress_id, place_id, attempts, last_error, status, next_attempt_at)
+		VALUES (?, ?, 1, ?, ?, ?)
+		ON CONFLICT(search_progress_id, place_id) DO UPDATE SET
+			attempts = attempts + 1,
+			last_error = excluded.last_error,
+			status = excluded.status,
+			next_attempt_at = excluded.next_attempt_at,
+			updated_at = CURRENT_TIMESTAMP
+	`, searchProgressID, placeID, lastError, PlaceRetryPending, nextAttemptAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to park place %s for retry: %v", placeID, err)
+	}
+	return nil
+}
+
+// Retorna os place IDs pendentes cuja próxima tentativa já venceu
+func ListDuePlaceRetries(db *sql.DB, now time.Time, limit int) ([]PlaceRetry, error) {
+	rows, err := db.Query(`
+		SELECT id, search_progress_id, place_id, attempts, last_error, status, next_attempt_at
+		FROM place_retry
+		WHERE status = ?
+		ORDER BY next_attempt_at
+	`, PlaceRetryPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list place retries: %v", err)
+	}
+	defer rows.Close()
+
+	var retries []PlaceRetry
+	for rows.Next() {
+		var retry PlaceRetry
+		var lastError sql.NullString
+		var nextAttemptAt sql.NullTime
+		err := rows.Scan(&retry.ID, &retry.SearchProgressID, &retry.PlaceID, &retry.Attempts, &lastError, &retry.Status, &nextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+		// A comparação é feita em Go: o SQLite guarda o horário como texto
+		if nextAttemptAt.Time.After(now) {
+			continue
+		}
+		retry.LastError = lastError.String
+		retry.NextAttemptAt = nextAttemptAt.Time
+		retries = append(retries, retry)
+		if limit > 0 && len(retries) >= limit {
+			break
+		}
+	}
+	return retries, rows.Err()
+}
+
+func MarkPlaceRetryDone(db *sql.DB, retryID int64) error {
+	_, err := db.Exec(`UPDATE place_retry SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, PlaceRetryDone, retryID)
+	if err != nil {
+		return fmt.Errorf("failed to mark place retry %d as done: %v", retryID, err)
+	}
+	return nil
+}
+
+// Registra mais uma tentativa que falhou. Com giveUp o place ID sai da fila de retentativas.
+func ReschedulePlaceRetry(db *sql.DB, retryID int64, lastError string, nextAttemptAt time.Time, giveUp bool) error {
+	status := PlaceRetryPending
+	if giveUp {
+		status = PlaceRetryFailed
+	}
+
+	_, err := db.Exec(`
+		UPDATE place_retry
+		SET attempts = attempts + 1, last_error = ?, status = ?, next_attempt_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, lastError, status, nextAttemptAt.UTC(), retryID)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule place retry %d: %v", retryID, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Estado de paginação de uma consulta da Text Search, identificada por
+// query/location/radius
+type QueryProgress struct {
+	Query          string
+	Location       string
+	Radius         int
+	PagesFetched   int
+	LeadsExtracted int
+	NextPageToken  string
+	UpdatedAt      time.Time
+}
+
+type QueryProgressStore interface {
+	LoadQueryProgress(query string, location string, radius int) (*QueryProgress, error)
+	SaveQueryProgress(progress QueryProgress) error
+	ClearQueryProgress(query string, location string, radius int) error
+}
+
+type SQLiteQueryProgressStore struct {
+	db *sql.DB
+}
+
+func NewQueryProgressStore(db *sql.DB) *SQLiteQueryProgressStore {
+	return &SQLiteQueryProgressStore{db: db}
+}
+
+// Retorna nil quando não existe progresso salvo para a consulta
+func (s *SQLiteQueryProgressStore) LoadQueryProgress(query string, location string, radius int) (*QueryProgress, error) {
+	progress := QueryProgress{Query: query, Location: location, Radius: radius}
+	var pagesFetched, leadsExtracted sql.NullInt64
+	var token sql.NullString
+	var updatedAt sql.NullTime
+
+	err := s.db.QueryRow(`
+		SELECT pages_fetched, leads_extracted, next_page_token, updated_at
+		FROM query_progress
+		WHERE query = ? AND location = ? AND radius = ?
+	`, query, location, radius).Scan(&pagesFetched, &leadsExtracted, &token, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load query progress: %v", err)
+	}
+
+	progress.PagesFetched = int(pagesFetched.Int64)
+	progress.LeadsExtracted = int(leadsExtracted.Int64)
+	progress.NextPageToken = token.String
+	progress.UpdatedAt = updatedAt.Time
+	return &progress, nil
+}
+
+func (s *SQLiteQueryProgressStore) SaveQueryProgress(progress QueryProgress) error {
+	_, err := s.db.Exec(`
+		INSERT INTO query_progress (query, location, radius, pages_fetched, leads_extracted, next_page_token, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(query, location, radius) DO UPDATE SET
+			pages_fetched = excluded.pages_fetched,
+			leads_extracted = excluded.leads_extracted,
+			next_page_token = excluded.next_page_token,
+			updated_at = excluded.updated_at
+	`, progress.Query, progress.Location, progress.Radius, progress.PagesFetched, progress.LeadsExtracted, progress.NextPageToken)
+	if err != nil {
+		return fmt.Errorf("failed to save query progress: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteQueryProgressStore) ClearQueryProgress(query string, location string, radius int) error {
+	_, err := s.db.Exec(`DELETE FROM query_progress WHERE query = ? AND location = ? AND radius = ?`, query, location, radius)
+	if err != nil {
+		return fmt.Errorf("failed to clear query progress: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Busca recorrente disparada pelo agendador a partir de uma expressão cron
+type SearchSchedule struct {
+	ID          int64
+	CategoriaID string
+	StateID     int
+	CityID      int
+	DistrictID  int
+	ZipcodeID   int
+	Mode        string
+	Radius      int
+	MaxResults  int
+	MaxPages    int
+	CronExpr    string
+	Enabled     bool
+	LastRunAt   *time.Time
+	NextRunAt   *time.Time
+	CreatedAt   time.Time
+}
+
+const selectScheduleSQL = `
+	SELECT id, categoria_id, state_id, city_id, district_id, zipcode_id, mode, radius,
+		max_results, max_pages, cron_expr, enabled, last_run_at, next_run_at, created_at
+	FROM search_schedule
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSchedule(row rowScanner) (*SearchSchedule, error) {
+	var schedule SearchSchedule
+	var categoriaID, mode sql.NullString
+	var stateID, cityID, districtID, zipcodeID, radius, maxResults, maxPages, enabled sql.NullInt64
+	var lastRunAt, nextRunAt, createdAt sql.NullTime
+
+	err := row.Scan(
+		&schedule.ID, &categoriaID, &stateID, &cityID, &districtID, &zipcodeID, &mode, &radius,
+		&maxResults, &maxPages, &schedule.CronExpr, &enabled, &lastRunAt, &nextRunAt, &createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.CategoriaID = categoriaID.String
+	schedule.StateID = int(stateID.Int64)
+	schedule.CityID = int(cityID.Int64)
+	schedule.DistrictID = int(districtID.Int64)
+	schedule.ZipcodeID = int(zipcodeID.Int64)
+	schedule.Mode = mode.String
+	schedule.Radius = int(radius.Int64)
+	schedule.MaxResults = int(maxResults.Int64)
+	schedule.MaxPages = int(maxPages.Int64)
+	schedule.Enabled = enabled.Int64 == 1
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	schedule.CreatedAt = createdAt.Time
+	return &schedule, nil
+}
+
+func InsertSchedule(db *sql.DB, schedule SearchSchedule) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO search_schedule (categoria_id, state_id, city_id, district_id, zipcode_id, mode, radius,
+			max_results, max_pages, cron_expr, enabled, next_run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, schedule.CategoriaID, nullIfZero(schedule.StateID), nullIfZero(schedule.CityID), nullIfZero(schedule.DistrictID),
+		nullIfZero(schedule.ZipcodeID), schedule.Mode, schedule.Radius, schedule.MaxResults, schedule.MaxPages,
+		schedule.CronExpr, boolToInt(schedule.Enabled), utcOrNil(schedule.NextRunAt))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert schedule: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+func GetScheduleByID(db *sql.DB, scheduleID int64) (*SearchSchedule, error) {
+	schedule, err := scanSchedule(db.QueryRow(selectScheduleSQL+" WHERE id = ?", scheduleID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Schedule with ID %d not found", scheduleID)
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func ListSchedules(db *sql.DB, onlyEnabled bool) ([]SearchSchedule, error) {
+	query := selectScheduleSQL
+	if onlyEnabled {
+		query += " WHERE enabled = 1"
+	}
+	query += " ORDER BY id"
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %v", err)
+	}
+	defer rows.Close()
+
+	var schedules []SearchSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func UpdateSchedule(db *sql.DB, schedule SearchSchedule) error {
+	_, err := db.Exec(`
+		UPDATE search_schedule
+		SET categoria_id = ?, state_id = ?, city_id = ?, district_id = ?, zipcode_id = ?, mode = ?, radius = ?,
+			max_results = ?, max_pages = ?, cron_expr = ?, enabled = ?, next_run_at = ?
+		WHERE id = ?
+	`, schedule.CategoriaID, nullIfZero(schedule.StateID), nullIfZero(schedule.CityID), nullIfZero(schedule.DistrictID),
+		nullIfZero(schedule.ZipcodeID), schedule.Mode, schedule.Radius, schedule.MaxResults, schedule.MaxPages,
+		schedule.CronExpr, boolToInt(schedule.Enabled), utcOrNil(schedule.NextRunAt), schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	return nil
+}
+
+func DeleteSchedule(db *sql.DB, scheduleID int64) error {
+	result, err := db.Exec(`DELETE FROM search_schedule WHERE id = ?`, scheduleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("Schedule with ID %d not found", scheduleID)
+	}
+	return nil
+}
+
+func MarkScheduleRun(db *sql.DB, scheduleID int64, lastRunAt time.Time, nextRunAt time.Time) error {
+	_, err := db.Exec(`UPDATE search_schedule SET last_run_at = ?, next_run_at = ? WHERE id = ?`,
+		lastRunAt.UTC(), nextRunAt.UTC(), scheduleID)
+	if err != nil {
+		return fmt.Errorf("failed to mark schedule run: %v", err)
+	}
+	return nil
+}
+
+func nullIfZero(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func utcOrNil(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.UTC()
+}
@@ -3,40 +3,182 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"time"
+)
+
+// Estados possíveis de uma busca na tabela search_progress
+const (
+	SearchStatusQueued      = "queued"
+	SearchStatusRunning     = "running"
+	SearchStatusDone        = "done"
+	SearchStatusFailed      = "failed"
+	SearchStatusCancelled   = "cancelled"   // cancelada pelo DELETE /searches/{id}
+	SearchStatusInterrupted = "interrupted" // parada no desligamento, é retomada ao subir de novo
 )
 
 type SearchProgress struct {
-	CategoriaID string
-	CountryID   string
-	StateID     string
-	CityID      string
-	DistrictID  string
-	ZipcodeID   string
-	Radius      int
-	SearchDone  int // 0 = Não concluído, 1 = Concluído
+	ID             int64
+	ScheduleID     int64 // 0 quando a busca não veio do agendador
+	CategoriaID    string
+	CountryID      string
+	StateID        string
+	CityID         string
+	DistrictID     string
+	ZipcodeID      string
+	Radius         int
+	Mode           string
+	MaxResults     int
+	MaxPages       int
+	PagesFetched   int
+	LeadsExtracted int
+	PlacesSkipped  int // já conhecidos em seen_place, sem chamada de Details
+	SearchDone     int // 0 = Não concluído, 1 = Concluído
+	Status         string
+	ErrorCount     int
+	LastError      string
+	SearchDate     time.Time
 }
 
-
 func InsertSearchProgress(db *sql.DB, progress SearchProgress) (int64, error) {
+	status := progress.Status
+	if status == "" {
+		status = SearchStatusQueued
+	}
+
 	query := `
-		INSERT INTO search_progress (categoria_id, country_id, state_id, city_id, district_id, zipcode_id, search_done)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO search_progress (categoria_id, country_id, state_id, city_id, district_id, zipcode_id, radius, search_done, status, schedule_id, mode, max_results, max_pages)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := db.Exec(query, progress.CategoriaID, progress.CountryID, progress.StateID, progress.CityID, progress.DistrictID, progress.ZipcodeID, progress.SearchDone)
+	result, err := db.Exec(query, progress.CategoriaID, nullIfEmpty(progress.CountryID), nullIfEmpty(progress.StateID), nullIfEmpty(progress.CityID), nullIfEmpty(progress.DistrictID), nullIfEmpty(progress.ZipcodeID), progress.Radius, progress.SearchDone, status, nullIfZero(int(progress.ScheduleID)), nullIfEmpty(progress.Mode), progress.MaxResults, progress.MaxPages)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert search progress: %v", err)
 	}
 
-	
 	return result.LastInsertId()
 }
 
+const searchProgressColumns = `
+	id, categoria_id, country_id, state_id, city_id, district_id, zipcode_id,
+	radius, mode, max_results, max_pages, pages_fetched, leads_extracted, places_skipped, search_done,
+	status, error_count, last_error, search_date, schedule_id
+`
 
+func GetSearchProgressByID(db *sql.DB, progressID int64) (*SearchProgress, error) {
+	row := db.QueryRow(`SELECT `+searchProgressColumns+` FROM search_progress WHERE id = ?`, progressID)
+	progress, err := scanSearchProgress(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Search progress with ID %d not found", progressID)
+		}
+		return nil, err
+	}
+	return progress, nil
+}
+
+// Buscas que ficaram na fila, em execução ou interrompidas quando o serviço
+// parou e precisam ser enfileiradas de novo
+func ListResumableSearches(db *sql.DB) ([]SearchProgress, error) {
+	rows, err := db.Query(`SELECT `+searchProgressColumns+` FROM search_progress WHERE status IN (?, ?, ?) ORDER BY id`,
+		SearchStatusQueued, SearchStatusRunning, SearchStatusInterrupted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resumable searches: %v", err)
+	}
+	defer rows.Close()
+
+	var searches []SearchProgress
+	for rows.Next() {
+		progress, err := scanSearchProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, *progress)
+	}
+	return searches, rows.Err()
+}
+
+func scanSearchProgress(row interface{ Scan(...interface{}) error }) (*SearchProgress, error) {
+	var progress SearchProgress
+	var categoriaID, countryID, stateID, cityID, districtID, zipcodeID, mode, status, lastError sql.NullString
+	var radius, maxResults, maxPages, pagesFetched, leadsExtracted, placesSkipped, searchDone, errorCount, scheduleID sql.NullInt64
+	var searchDate sql.NullTime
+
+	err := row.Scan(
+		&progress.ID, &categoriaID, &countryID, &stateID, &cityID, &districtID, &zipcodeID,
+		&radius, &mode, &maxResults, &maxPages, &pagesFetched, &leadsExtracted, &placesSkipped, &searchDone,
+		&status, &errorCount, &lastError, &searchDate, &scheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	progress.CategoriaID = categoriaID.String
+	progress.CountryID = countryID.String
+	progress.StateID = stateID.String
+	progress.CityID = cityID.String
+	progress.DistrictID = districtID.String
+	progress.ZipcodeID = zipcodeID.String
+	progress.Radius = int(radius.Int64)
+	progress.Mode = mode.String
+	progress.MaxResults = int(maxResults.Int64)
+	progress.MaxPages = int(maxPages.Int64)
+	progress.PagesFetched = int(pagesFetched.Int64)
+	progress.LeadsExtracted = int(leadsExtracted.Int64)
+	progress.PlacesSkipped = int(placesSkipped.Int64)
+	progress.SearchDone = int(searchDone.Int64)
+	progress.Status = status.String
+	progress.ErrorCount = int(errorCount.Int64)
+	progress.LastError = lastError.String
+	progress.SearchDate = searchDate.Time
+	progress.ScheduleID = scheduleID.Int64
+
+	return &progress, nil
+}
+
+// Soma um lead publicado fora da execução original da busca (ex.: retentativas)
+func IncrementSearchProgressLeads(db *sql.DB, progressID int64) error {
+	_, err := db.Exec(`UPDATE search_progress SET leads_extracted = leads_extracted + 1 WHERE id = ?`, progressID)
+	if err != nil {
+		return fmt.Errorf("failed to update search progress: %v", err)
+	}
+	return nil
+}
+
+func UpdateSearchProgressStatus(db *sql.DB, progressID int64, status string) error {
+	searchDone := 0
+	if status == SearchStatusDone {
+		searchDone = 1
+	}
+
+	query := `
+		UPDATE search_progress
+		SET status = ?, search_done = ?
+		WHERE id = ?
+	`
+	_, err := db.Exec(query, status, searchDone, progressID)
+	if err != nil {
+		return fmt.Errorf("failed to update search progress status: %v", err)
+	}
+	return nil
+}
+
+// Incrementa o contador de erros da busca e guarda a última mensagem
+func RecordSearchError(db *sql.DB, progressID int64, message string) error {
+	query := `
+		UPDATE search_progress
+		SET error_count = error_count + 1, last_error = ?
+		WHERE id = ?
+	`
+	_, err := db.Exec(query, message, progressID)
+	if err != nil {
+		return fmt.Errorf("failed to record search error: %v", err)
+	}
+	return nil
+}
 
 func UpdateSearchProgressPage(db *sql.DB, progressID int64, currentPage int) error {
 	query := `
 		UPDATE search_progress
-		SET search_done = ?
+		SET pages_fetched = ?
 		WHERE id = ?
 	`
 	_, err := db.Exec(query, currentPage, progressID)
@@ -46,3 +188,10 @@ func UpdateSearchProgressPage(db *sql.DB, progressID int64, currentPage int) err
 	return nil
 }
 
+// Buscas por cidade ou bairro não têm zipcode; grava NULL em vez de string vazia
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
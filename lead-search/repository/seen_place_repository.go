@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// O SQLite limita a quantidade de parâmetros por consulta
+const seenPlaceChunkSize = 500
+
+// Retorna quando os detalhes de cada place ID já conhecido foram publicados
+// pela última vez. Place IDs nunca vistos ficam fora do mapa.
+func GetPlaceFetchTimes(db *sql.DB, placeIDs []string) (map[string]time.Time, error) {
+	fetched := make(map[string]time.Time)
+	for start := 0; start < len(placeIDs); start += seenPlaceChunkSize {
+		end := start + seenPlaceChunkSize
+		if end > len(placeIDs) {
+			end = len(placeIDs)
+		}
+		chunk := placeIDs[start:end]
+
+		args := make([]interface{}, len(chunk))
+		for i, placeID := range chunk {
+			args[i] = placeID
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+
+		rows, err := db.Query(`SELECT place_id, last_fetched_at FROM seen_place WHERE place_id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load seen places: %v", err)
+		}
+		for rows.Next() {
+			var placeID string
+			var lastFetchedAt sql.NullTime
+			if err := rows.Scan(&placeID, &lastFetchedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			fetched[placeID] = lastFetchedAt.Time
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fetched, nil
+}
+
+// Registra que os detalhes do place ID foram obtidos e publicados agora
+func MarkPlaceFetched(db *sql.DB, placeID string, searchProgressID int64, fetchedAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO seen_place (place_id, search_progress_id, first_seen_at, last_fetched_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(place_id) DO UPDATE SET
+			search_progress_id = excluded.search_progress_id,
+			last_fetched_at = excluded.last_fetched_at
+	`, placeID, nullIfZero(int(searchProgressID)), fetchedAt.UTC(), fetchedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to mark place %s as fetched: %v", placeID, err)
+	}
+	return nil
+}
+
+// Soma os lugares que a busca deixou de consultar por já serem conhecidos
+func AddSearchProgressSkipped(db *sql.DB, progressID int64, skipped int) error {
+	_, err := db.Exec(`UPDATE search_progress SET places_skipped = places_skipped + ? WHERE id = ?`, skipped, progressID)
+	if err != nil {
+		return fmt.Errorf("failed to update search progress: %v", err)
+	}
+	return nil
+}
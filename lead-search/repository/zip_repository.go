@@ -56,3 +56,68 @@ func GetFirstZipCodeInRange(db *sql.DB, districtID int) (string, error) {
 	}
 	return startZip, nil
 }
+
+func GetLocationInfoByDistrictID(db *sql.DB, districtID int) (*LocationInfo, error) {
+	query := `
+		SELECT
+			d.id AS district_id, d.name AS district_name,
+			c.id AS city_id, c.name AS city_name,
+			s.id AS state_id, s.name AS state_name,
+			co.id AS country_id, co.name AS country_name
+		FROM district d
+		JOIN city c ON d.city_id = c.id
+		JOIN state s ON c.state_id = s.id
+		JOIN country co ON s.country_id = co.id
+		WHERE d.id = ?
+	`
+	var location LocationInfo
+	err := db.QueryRow(query, districtID).Scan(
+		&location.DistrictID, &location.DistrictName,
+		&location.CityID, &location.CityName, &location.StateID, &location.StateName,
+		&location.CountryID, &location.CountryName,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("No location found for district ID %d", districtID)
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+func GetLocationInfoByCityID(db *sql.DB, cityID int) (*LocationInfo, error) {
+	query := `
+		SELECT
+			c.id AS city_id, c.name AS city_name,
+			s.id AS state_id, s.name AS state_name,
+			co.id AS country_id, co.name AS country_name
+		FROM city c
+		JOIN state s ON c.state_id = s.id
+		JOIN country co ON s.country_id = co.id
+		WHERE c.id = ?
+	`
+	var location LocationInfo
+	err := db.QueryRow(query, cityID).Scan(
+		&location.CityID, &location.CityName, &location.StateID, &location.StateName,
+		&location.CountryID, &location.CountryName,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("No location found for city ID %d", cityID)
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+func GetZipRangeByID(db *sql.DB, zipcodeID int) (string, string, error) {
+	var startZip, endZip sql.NullString
+	err := db.QueryRow("SELECT start_zip, end_zip FROM zipcode WHERE id = ?", zipcodeID).Scan(&startZip, &endZip)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", fmt.Errorf("Zipcode with ID %d not found", zipcodeID)
+		}
+		return "", "", err
+	}
+	return startZip.String, endZip.String, nil
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Aceita expressões de 5 campos (minuto hora dia mês dia-da-semana), descritores
+// como @weekly e o prefixo CRON_TZ=America/Sao_Paulo
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+const schedulerInterval = 30 * time.Second
+
+type searchScheduler struct {
+	db    *sql.DB
+	queue *searchQueue
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newSearchScheduler(db *sql.DB, queue *searchQueue) *searchScheduler {
+	return &searchScheduler{
+		db:    db,
+		queue: queue,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// O primeiro tick roda logo na inicialização, então agendamentos cujo
+// next_run_at passou enquanto o serviço estava parado disparam uma única vez
+func (s *searchScheduler) Start() {
+	go func() {
+		defer close(s.done)
+		s.tick(time.Now())
+
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.tick(now)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Println("Agendador de buscas iniciado")
+}
+
+// Para de disparar agendamentos. Como no cron.Stop, o contexto devolvido
+// termina quando o tick em andamento acaba.
+func (s *searchScheduler) Stop() context.Context {
+	close(s.stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+	return ctx
+}
+
+func (s *searchScheduler) tick(now time.Time) {
+	schedules, err := repository.ListSchedules(s.db, true)
+	if err != nil {
+		log.Printf("Erro ao carregar agendamentos: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if schedule.NextRunAt != nil && schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		cronSchedule, err := cronParser.Parse(schedule.CronExpr)
+		if err != nil {
+			log.Printf("Expressão cron inválida no agendamento %d: %v", schedule.ID, err)
+			continue
+		}
+
+		if schedule.NextRunAt != nil {
+			if missed := cronSchedule.Next(*schedule.NextRunAt); !missed.After(now) {
+				log.Printf("Agendamento %d perdeu execuções desde %s, executando uma vez", schedule.ID, schedule.NextRunAt.Format(time.RFC3339))
+			}
+			s.fire(schedule)
+		}
+
+		// Agendamentos sem next_run_at (criados antes de existir o campo) só
+		// calculam a próxima execução
+		nextRun := cronSchedule.Next(now)
+		err = repository.MarkScheduleRun(s.db, schedule.ID, now, nextRun)
+		if err != nil {
+			log.Printf("Erro ao atualizar o agendamento %d: %v", schedule.ID, err)
+		}
+	}
+}
+
+func (s *searchScheduler) fire(schedule repository.SearchSchedule) {
+	jobs, err := scheduleJobs(s.db, schedule)
+	if err != nil {
+		log.Printf("Erro ao criar as buscas do agendamento %d: %v", schedule.ID, err)
+		return
+	}
+
+	var created []searchJob
+	for _, job := range jobs {
+		job, err := createSearchJob(s.db, job)
+		if err != nil {
+			log.Printf("Erro ao agendar busca do agendamento %d: %v", schedule.ID, err)
+			continue
+		}
+		created = append(created, job)
+	}
+
+	go func() {
+		for _, job := range created {
+			s.queue.EnqueueWait(job)
+		}
+	}()
+	log.Printf("Agendamento %d disparou %d buscas", schedule.ID, len(created))
+}
+
+// Uma busca por zipcode abaixo do alvo, ou uma única varredura da cidade/bairro
+func scheduleJobs(db *sql.DB, schedule repository.SearchSchedule) ([]searchJob, error) {
+	base := searchJob{
+		ScheduleID: schedule.ID,
+		Mode:       schedule.Mode,
+		CategoryID: schedule.CategoriaID,
+		Radius:     schedule.Radius,
+		MaxResults: schedule.MaxResults,
+		MaxPages:   schedule.MaxPages,
+	}
+
+	if schedule.ZipcodeID != 0 {
+		base.ZipcodeID = schedule.ZipcodeID
+		return []searchJob{base}, nil
+	}
+
+	if schedule.Mode == searchModeSweep {
+		base.DistrictID = schedule.DistrictID
+		base.CityID = schedule.CityID
+		return []searchJob{base}, nil
+	}
+
+	zipcodeIDs, err := repository.ListZipcodeIDs(db, schedule.StateID, schedule.CityID, schedule.DistrictID)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]searchJob, 0, len(zipcodeIDs))
+	for _, zipcodeID := range zipcodeIDs {
+		job := base
+		job.ZipcodeID = zipcodeID
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+type scheduleRequest struct {
+	CategoryID int    `json:"category_id"`
+	StateID    int    `json:"state_id"`
+	CityID     int    `json:"city_id"`
+	DistrictID int    `json:"district_id"`
+	ZipcodeID  int    `json:"zipcode_id"`
+	Mode       string `json:"mode"`
+	Radius     int    `json:"radius"`
+	MaxResults int    `json:"max_results"`
+	MaxPages   int    `json:"max_pages"`
+	Cron       string `json:"cron"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+type scheduleResponse struct {
+	ID         int64      `json:"id"`
+	CategoryID string     `json:"category_id"`
+	StateID    int        `json:"state_id,omitempty"`
+	CityID     int        `json:"city_id,omitempty"`
+	DistrictID int        `json:"district_id,omitempty"`
+	ZipcodeID  int        `json:"zipcode_id,omitempty"`
+	Mode       string     `json:"mode"`
+	Radius     int        `json:"radius"`
+	MaxResults int        `json:"max_results"`
+	MaxPages   int        `json:"max_pages"`
+	Cron       string     `json:"cron"`
+	Enabled    bool       `json:"enabled"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	NextRunAt  *time.Time `json:"next_run_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func toScheduleResponse(schedule repository.SearchSchedule) scheduleResponse {
+	return scheduleResponse{
+		ID:         schedule.ID,
+		CategoryID: schedule.CategoriaID,
+		StateID:    schedule.StateID,
+		CityID:     schedule.CityID,
+		DistrictID: schedule.DistrictID,
+		ZipcodeID:  schedule.ZipcodeID,
+		Mode:       schedule.Mode,
+		Radius:     schedule.Radius,
+		MaxResults: schedule.MaxResults,
+		MaxPages:   schedule.MaxPages,
+		Cron:       schedule.CronExpr,
+		Enabled:    schedule.Enabled,
+		LastRunAt:  schedule.LastRunAt,
+		NextRunAt:  schedule.NextRunAt,
+		CreatedAt:  schedule.CreatedAt,
+	}
+}
+
+// Valida o corpo da requisição e preenche o agendamento, recalculando a próxima execução
+func applyScheduleRequest(db *sql.DB, req scheduleRequest, schedule *repository.SearchSchedule) error {
+	if req.CategoryID == 0 || req.Radius <= 0 || req.Cron == "" {
+		return fmt.Errorf("category_id, radius and cron are required")
+	}
+	if _, err := repository.GetCategoryNameByID(db, strconv.Itoa(req.CategoryID)); err != nil {
+		return err
+	}
+	if req.StateID == 0 && req.CityID == 0 && req.DistrictID == 0 && req.ZipcodeID == 0 {
+		return fmt.Errorf("state_id, city_id, district_id or zipcode_id is required")
+	}
+
+	if req.Mode == "" {
+		req.Mode = searchModeRadius
+	}
+	if req.Mode != searchModeRadius && req.Mode != searchModeSweep {
+		return fmt.Errorf("mode must be radius or sweep")
+	}
+	if req.Mode == searchModeSweep && req.ZipcodeID == 0 && req.CityID == 0 && req.DistrictID == 0 {
+		return fmt.Errorf("sweep schedules need a city_id, district_id or zipcode_id")
+	}
+
+	if req.MaxPages <= 0 || req.MaxPages > googleplaces.MaxTextSearchPages {
+		req.MaxPages = googleplaces.MaxTextSearchPages
+	}
+	if req.MaxResults < 0 {
+		return fmt.Errorf("max_results must not be negative")
+	}
+	if req.MaxResults == 0 && req.Mode == searchModeRadius {
+		req.MaxResults = defaultMaxResults
+	}
+
+	cronSchedule, err := cronParser.Parse(req.Cron)
+	if err != nil {
+		return fmt.Errorf("invalid cron expression: %v", err)
+	}
+	nextRun := cronSchedule.Next(time.Now())
+
+	schedule.CategoriaID = strconv.Itoa(req.CategoryID)
+	schedule.StateID = req.StateID
+	schedule.CityID = req.CityID
+	schedule.DistrictID = req.DistrictID
+	schedule.ZipcodeID = req.ZipcodeID
+	schedule.Mode = req.Mode
+	schedule.Radius = req.Radius
+	schedule.MaxResults = req.MaxResults
+	schedule.MaxPages = req.MaxPages
+	schedule.CronExpr = req.Cron
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+	schedule.NextRunAt = &nextRun
+	return nil
+}
+
+func schedulesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	totalRequests.WithLabelValues("/schedules", r.Method).Inc()
+
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := repository.ListSchedules(db, false)
+		if err != nil {
+			totalErrors.WithLabelValues("/schedules", "list_failed").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]scheduleResponse, 0, len(schedules))
+		for _, schedule := range schedules {
+			response = append(response, toScheduleResponse(schedule))
+		}
+		writeJSON(w, http.StatusOK, response)
+
+	case http.MethodPost:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			totalErrors.WithLabelValues("/schedules", "invalid_body").Inc()
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var schedule repository.SearchSchedule
+		if err := applyScheduleRequest(db, req, &schedule); err != nil {
+			totalErrors.WithLabelValues("/schedules", "invalid_schedule").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		scheduleID, err := repository.InsertSchedule(db, schedule)
+		if err != nil {
+			totalErrors.WithLabelValues("/schedules", "insert_failed").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		created, err := repository.GetScheduleByID(db, scheduleID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Agendamento %d criado: %s", scheduleID, schedule.CronExpr)
+		writeJSON(w, http.StatusCreated, toScheduleResponse(*created))
+
+	default:
+		totalErrors.WithLabelValues("/schedules", "invalid_method").Inc()
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+func scheduleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	totalRequests.WithLabelValues("/schedules/{id}", r.Method).Inc()
+
+	scheduleID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		totalErrors.WithLabelValues("/schedules/{id}", "invalid_id").Inc()
+		http.Error(w, "Invalid schedule id", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := repository.GetScheduleByID(db, scheduleID)
+	if err != nil {
+		totalErrors.WithLabelValues("/schedules/{id}", "not_found").Inc()
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, toScheduleResponse(*schedule))
+
+	case http.MethodPut:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			totalErrors.WithLabelValues("/schedules/{id}", "invalid_body").Inc()
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := applyScheduleRequest(db, req, schedule); err != nil {
+			totalErrors.WithLabelValues("/schedules/{id}", "invalid_schedule").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := repository.UpdateSchedule(db, *schedule); err != nil {
+			totalErrors.WithLabelValues("/schedules/{id}", "update_failed").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Agendamento %d atualizado: %s", schedule.ID, schedule.CronExpr)
+		writeJSON(w, http.StatusOK, toScheduleResponse(*schedule))
+
+	case http.MethodDelete:
+		if err := repository.DeleteSchedule(db, scheduleID); err != nil {
+			totalErrors.WithLabelValues("/schedules/{id}", "delete_failed").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Agendamento %d removido", scheduleID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		totalErrors.WithLabelValues("/schedules/{id}", "invalid_method").Inc()
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+
+	"shared/leadmessage"
+)
+
+type memoryPublisher struct {
+	leads []leadmessage.LeadMessage
+}
+
+func (p *memoryPublisher) Publish(lead leadmessage.LeadMessage) error {
+	p.leads = append(p.leads, lead)
+	return nil
+}
+
+func setupTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := setupDatabase(filepath.Join(t.TempDir(), "geo.db"))
+	if err != nil {
+		t.Fatalf("setupDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	seed := []string{
+		`INSERT INTO country (id, name) VALUES (1, 'Brasil')`,
+		`INSERT INTO state (id, name, country_id) VALUES (1, 'São Paulo', 1)`,
+		`INSERT INTO city (id, name, state_id) VALUES (1, 'São Paulo', 1)`,
+		`INSERT INTO district (id, name, city_id) VALUES (1, 'Bela Vista', 1)`,
+		`INSERT INTO zipcode (id, start_zip, end_zip, district_id) VALUES (1, '01310-100', '01310-999', 1)`,
+		`INSERT INTO categoria (id, nome) VALUES (1, 'padaria')`,
+	}
+	for _, stmt := range seed {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed %q: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+// Executa uma busca por raio inteira contra as respostas gravadas em testdata/places
+func TestSearchPipelineWithFixtures(t *testing.T) {
+	t.Setenv("PAGE_TOKEN_DELAY", "0s")
+
+	db := setupTestDatabase(t)
+	publisher := &memoryPublisher{}
+	queue := &searchQueue{
+		db:        db,
+		provider:  googleplaces.NewFixtureProvider(filepath.Join("testdata", "places")),
+		publisher: publisher,
+	}
+
+	job, err := createSearchJob(db, searchJob{
+		Mode:       searchModeRadius,
+		CategoryID: "1",
+		ZipcodeID:  1,
+		Radius:     500,
+		MaxResults: defaultMaxResults,
+		MaxPages:   googleplaces.MaxTextSearchPages,
+	})
+	if err != nil {
+		t.Fatalf("createSearchJob: %v", err)
+	}
+
+	queue.run(context.Background(), job)
+
+	if len(publisher.leads) != 2 {
+		t.Fatalf("expected 2 published leads, got %d", len(publisher.leads))
+	}
+	lead := publisher.leads[0]
+	if lead.PlaceID != "place_a" || lead.Category != "padaria" || lead.SearchCity != "São Paulo" || lead.Radius != 500 {
+		t.Errorf("unexpected first lead: %+v", lead)
+	}
+	if lead.FormattedAddress != "Rua Haddock Lobo, 354, Cerqueira César" {
+		t.Errorf("unexpected address: %v", lead.FormattedAddress)
+	}
+	if err := lead.Validate(); err != nil {
+		t.Errorf("expected a valid lead message: %v", err)
+	}
+
+	progress, err := repository.GetSearchProgressByID(db, job.ProgressID)
+	if err != nil {
+		t.Fatalf("GetSearchProgressByID: %v", err)
+	}
+	if progress.Status != repository.SearchStatusDone || progress.SearchDone != 1 {
+		t.Errorf("expected search to be done, got status %s", progress.Status)
+	}
+	if progress.PagesFetched != 2 || progress.LeadsExtracted != 2 {
+		t.Errorf("expected 2 pages and 2 leads, got %d pages and %d leads", progress.PagesFetched, progress.LeadsExtracted)
+	}
+	// place_c não tem detalhes (NOT_FOUND) e fica registrado como erro
+	if progress.ErrorCount != 1 {
+		t.Errorf("expected 1 recorded error, got %d", progress.ErrorCount)
+	}
+
+	var zipcodeStatus int
+	if err := db.QueryRow(`SELECT status FROM zipcode WHERE id = 1`).Scan(&zipcodeStatus); err != nil {
+		t.Fatal(err)
+	}
+	if zipcodeStatus != repository.LocationStatusDone {
+		t.Errorf("expected zipcode status %d, got %d", repository.LocationStatusDone, zipcodeStatus)
+	}
+
+	costs, err := repository.GetSearchAPICosts(db, job.ProgressID)
+	if err != nil {
+		t.Fatalf("GetSearchAPICosts: %v", err)
+	}
+	calls := make(map[string]int)
+	for _, cost := range costs {
+		calls[cost.SKU] = cost.Calls
+	}
+	if calls[googleplaces.SKUGeocoding] != 1 || calls[googleplaces.SKUTextSearch] != 2 || calls[googleplaces.SKUPlaceDetails] != 3 {
+		t.Errorf("unexpected API calls in ledger: %v", calls)
+	}
+
+	cached, err := repository.NewGeocodeCacheStore(db).LoadGeocode("01310100")
+	if err != nil || cached == nil {
+		t.Fatalf("expected geocode cache entry, got %v (err %v)", cached, err)
+	}
+}
+
+type slowDetailsProvider struct {
+	googleplaces.FixtureProvider
+}
+
+// Lugares com índice menor demoram mais, para que terminem fora de ordem
+func (p *slowDetailsProvider) Details(ctx context.Context, placeID string) (map[string]interface{}, error) {
+	index, _ := strconv.Atoi(placeID)
+	time.Sleep(time.Duration(10-index) * time.Millisecond)
+	return map[string]interface{}{"PlaceID": placeID}, nil
+}
+
+func TestFetchPlaceDetailsKeepsSearchOrder(t *testing.T) {
+	service := googleplaces.NewServiceWithProvider(&slowDetailsProvider{})
+	placeIDs := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+	var got []string
+	for result := range fetchPlaceDetails(context.Background(), service, placeIDs, 4) {
+		got = append(got, result.PlaceID)
+	}
+	if strings.Join(got, ",") != strings.Join(placeIDs, ",") {
+		t.Errorf("expected results in search order, got %v", got)
+	}
+}
+
+func TestFetchPlaceDetailsStopsWhenCancelled(t *testing.T) {
+	service := googleplaces.NewServiceWithProvider(&slowDetailsProvider{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := 0
+	for range fetchPlaceDetails(ctx, service, []string{"0", "1", "2", "3", "4", "5"}, 2) {
+		received++
+		cancel()
+	}
+	if received >= 6 {
+		t.Errorf("expected cancellation to stop the results, got %d", received)
+	}
+}
+
+// Publisher que simula um SIGTERM logo depois do primeiro lead publicado
+type interruptingPublisher struct {
+	memoryPublisher
+	stop context.CancelCauseFunc
+}
+
+func (p *interruptingPublisher) Publish(lead leadmessage.LeadMessage) error {
+	p.stop(errSearchInterrupted)
+	return p.memoryPublisher.Publish(lead)
+}
+
+func TestSearchInterruptedOnShutdownParksRemainingPlaces(t *testing.T) {
+	t.Setenv("PAGE_TOKEN_DELAY", "0s")
+
+	db := setupTestDatabase(t)
+	ctx, stop := context.WithCancelCause(context.Background())
+	defer stop(nil)
+	publisher := &interruptingPublisher{stop: stop}
+	queue := &searchQueue{
+		db:        db,
+		provider:  googleplaces.NewFixtureProvider(filepath.Join("testdata", "places")),
+		publisher: publisher,
+	}
+
+	job, err := createSearchJob(db, searchJob{
+		Mode:       searchModeRadius,
+		CategoryID: "1",
+		ZipcodeID:  1,
+		Radius:     500,
+		MaxResults: defaultMaxResults,
+		MaxPages:   googleplaces.MaxTextSearchPages,
+	})
+	if err != nil {
+		t.Fatalf("createSearchJob: %v", err)
+	}
+
+	queue.run(ctx, job)
+
+	progress, err := repository.GetSearchProgressByID(db, job.ProgressID)
+	if err != nil {
+		t.Fatalf("GetSearchProgressByID: %v", err)
+	}
+	if progress.Status != repository.SearchStatusInterrupted {
+		t.Errorf("expected search to be interrupted, got status %s", progress.Status)
+	}
+	if progress.Mode != searchModeRadius || progress.MaxResults != defaultMaxResults {
+		t.Errorf("expected job options to be saved for resuming, got %+v", progress)
+	}
+
+	retries, err := repository.ListDuePlaceRetries(db, time.Now(), 0)
+	if err != nil {
+		t.Fatalf("ListDuePlaceRetries: %v", err)
+	}
+	var parked []string
+	for _, retry := range retries {
+		parked = append(parked, retry.PlaceID)
+	}
+	if len(publisher.leads)+len(parked) != 3 {
+		t.Errorf("expected published and parked places to cover the search, got %d published and %v parked", len(publisher.leads), parked)
+	}
+
+	resumable, err := repository.ListResumableSearches(db)
+	if err != nil || len(resumable) != 1 || resumable[0].ID != job.ProgressID {
+		t.Errorf("expected search %d to be resumable, got %+v (err %v)", job.ProgressID, resumable, err)
+	}
+}
+
+// Uma segunda busca sobre a mesma área não paga de novo os detalhes dos lugares já publicados
+func TestSearchSkipsPlacesAlreadyPublished(t *testing.T) {
+	t.Setenv("PAGE_TOKEN_DELAY", "0s")
+
+	db := setupTestDatabase(t)
+	publisher := &memoryPublisher{}
+	queue := &searchQueue{
+		db:        db,
+		provider:  googleplaces.NewFixtureProvider(filepath.Join("testdata", "places")),
+		publisher: publisher,
+	}
+
+	var second searchJob
+	for i := 0; i < 2; i++ {
+		job, err := createSearchJob(db, searchJob{
+			Mode:       searchModeRadius,
+			CategoryID: "1",
+			ZipcodeID:  1,
+			Radius:     500,
+			MaxResults: defaultMaxResults,
+			MaxPages:   googleplaces.MaxTextSearchPages,
+		})
+		if err != nil {
+			t.Fatalf("createSearchJob: %v", err)
+		}
+		queue.run(context.Background(), job)
+		second = job
+	}
+
+	if len(publisher.leads) != 2 {
+		t.Fatalf("expected only the first search to publish leads, got %d", len(publisher.leads))
+	}
+	progress, err := repository.GetSearchProgressByID(db, second.ProgressID)
+	if err != nil {
+		t.Fatalf("GetSearchProgressByID: %v", err)
+	}
+	if progress.PlacesSkipped != 2 || progress.LeadsExtracted != 0 {
+		t.Errorf("expected 2 skipped places and no leads, got %d skipped and %d leads", progress.PlacesSkipped, progress.LeadsExtracted)
+	}
+
+	// place_c falhou (NOT_FOUND) e por isso é consultado de novo
+	costs, err := repository.GetSearchAPICosts(db, second.ProgressID)
+	if err != nil {
+		t.Fatalf("GetSearchAPICosts: %v", err)
+	}
+	for _, cost := range costs {
+		if cost.SKU == googleplaces.SKUPlaceDetails && cost.Calls != 1 {
+			t.Errorf("expected 1 details call on the second search, got %d", cost.Calls)
+		}
+	}
+}
+
+// Uma busca que falhou não deixa o zipcode em andamento para sempre
+func TestFailedSearchIsNotPending(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	job, err := createSearchJob(db, searchJob{
+		Mode:       searchModeRadius,
+		CategoryID: "1",
+		ZipcodeID:  1,
+		Radius:     500,
+		MaxResults: defaultMaxResults,
+		MaxPages:   googleplaces.MaxTextSearchPages,
+	})
+	if err != nil {
+		t.Fatalf("createSearchJob: %v", err)
+	}
+	if err := repository.MarkZipcodeInProgress(db, 1); err != nil {
+		t.Fatalf("MarkZipcodeInProgress: %v", err)
+	}
+	if err := repository.MarkCategoryInProgress(db, "1"); err != nil {
+		t.Fatalf("MarkCategoryInProgress: %v", err)
+	}
+	if err := repository.UpdateSearchProgressStatus(db, job.ProgressID, repository.SearchStatusFailed); err != nil {
+		t.Fatalf("UpdateSearchProgressStatus: %v", err)
+	}
+	if err := repository.RefreshZipcodeStatus(db, 1); err != nil {
+		t.Fatalf("RefreshZipcodeStatus: %v", err)
+	}
+	if err := repository.RefreshCategoryStatus(db, "1"); err != nil {
+		t.Fatalf("RefreshCategoryStatus: %v", err)
+	}
+
+	// Sem nenhuma busca concluída o zipcode e a categoria voltam a pendente
+	var zipcodeStatus, categoryStatus int
+	if err := db.QueryRow(`SELECT status FROM zipcode WHERE id = 1`).Scan(&zipcodeStatus); err != nil {
+		t.Fatal(err)
+	}
+	if zipcodeStatus != repository.LocationStatusPending {
+		t.Errorf("expected zipcode status %d after a failed search, got %d", repository.LocationStatusPending, zipcodeStatus)
+	}
+	if err := db.QueryRow(`SELECT status FROM categoria WHERE id = '1'`).Scan(&categoryStatus); err != nil {
+		t.Fatal(err)
+	}
+	if categoryStatus != repository.LocationStatusPending {
+		t.Errorf("expected category status %d after a failed search, got %d", repository.LocationStatusPending, categoryStatus)
+	}
+}
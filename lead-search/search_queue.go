@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"lead-search/googleplaces"
+	"lead-search/repository"
+)
+
+type searchJob struct {
+	ProgressID int64
+	ScheduleID int64
+	Mode       string
+	CategoryID string
+	ZipcodeID  int
+	DistrictID int
+	CityID     int
+	Radius     int
+	MaxResults int
+	MaxPages   int
+}
+
+// Causas de cancelamento do contexto de uma busca em execução
+var (
+	errSearchCancelled   = errors.New("search cancelled")
+	errSearchInterrupted = errors.New("search interrupted by shutdown")
+)
+
+// Fila em memória que executa as buscas fora da requisição HTTP.
+// O ID do job é o mesmo ID da linha em search_progress.
+type searchQueue struct {
+	jobs      chan searchJob
+	db        *sql.DB
+	provider  googleplaces.PlacesProvider
+	limiter   *googleplaces.Limiter
+	publisher leadPublisher
+
+	// Cancelado no desligamento; o contexto de cada busca deriva dele
+	ctx     context.Context
+	stop    context.CancelCauseFunc
+	workers sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[int64]context.CancelCauseFunc
+}
+
+func newSearchQueue(db *sql.DB, provider googleplaces.PlacesProvider, limiter *googleplaces.Limiter, publisher leadPublisher, workers int, size int) *searchQueue {
+	q := &searchQueue{
+		jobs:      make(chan searchJob, size),
+		db:        db,
+		provider:  provider,
+		limiter:   limiter,
+		publisher: publisher,
+		running:   make(map[int64]context.CancelCauseFunc),
+	}
+	q.ctx, q.stop = context.WithCancelCause(context.Background())
+
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go q.worker(i + 1)
+	}
+	log.Printf("Fila de buscas iniciada com %d workers (capacidade %d)", workers, size)
+
+	return q
+}
+
+func (q *searchQueue) Enqueue(job searchJob) error {
+	if q.ctx.Err() != nil {
+		return fmt.Errorf("search queue is shutting down")
+	}
+
+	select {
+	case q.jobs <- job:
+		log.Printf("Busca %d enfileirada", job.ProgressID)
+		return nil
+	default:
+		return fmt.Errorf("search queue is full")
+	}
+}
+
+// Espera até haver espaço na fila; usado para agendar lotes maiores que a fila.
+// No desligamento o job continua como queued e é retomado ao subir de novo.
+func (q *searchQueue) EnqueueWait(job searchJob) {
+	select {
+	case q.jobs <- job:
+	case <-q.ctx.Done():
+	}
+}
+
+// Cancela uma busca. Em execução, o contexto dela é cancelado e o worker
+// grava o status; ainda na fila, ela é marcada como cancelada e pulada pelo
+// worker. O bool indica se a busca ainda estava em execução.
+func (q *searchQueue) Cancel(progressID int64) (bool, error) {
+	q.mu.Lock()
+	cancel, running := q.running[progressID]
+	q.mu.Unlock()
+	if running {
+		log.Printf("Cancelando a busca %d em execução", progressID)
+		cancel(errSearchCancelled)
+		return true, nil
+	}
+
+	progress, err := repository.GetSearchProgressByID(q.db, progressID)
+	if err != nil {
+		return false, err
+	}
+	if progress.Status != repository.SearchStatusQueued && progress.Status != repository.SearchStatusInterrupted {
+		return false, fmt.Errorf("search %d is already %s", progressID, progress.Status)
+	}
+	log.Printf("Cancelando a busca %d na fila", progressID)
+	return false, repository.UpdateSearchProgressStatus(q.db, progressID, repository.SearchStatusCancelled)
+}
+
+// Para de aceitar buscas e interrompe as que estão em execução. Cada worker
+// grava o status interrupted da sua busca; os next_page_token já ficam salvos
+// a cada página. Espera os workers terminarem até o fim do ctx.
+func (q *searchQueue) Shutdown(ctx context.Context) error {
+	q.stop(errSearchInterrupted)
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Fila de buscas encerrada")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("search queue shutdown: %v", ctx.Err())
+	}
+}
+
+// Enfileira de novo as buscas que não terminaram na última execução do serviço
+func (q *searchQueue) Resume() {
+	searches, err := repository.ListResumableSearches(q.db)
+	if err != nil {
+		log.Printf("Erro ao carregar as buscas para retomar: %v", err)
+		return
+	}
+	if len(searches) == 0 {
+		return
+	}
+
+	jobs := make([]searchJob, 0, len(searches))
+	for i := range searches {
+		jobs = append(jobs, searchJobFromProgress(&searches[i]))
+	}
+	go func() {
+		for _, job := range jobs {
+			q.EnqueueWait(job)
+		}
+	}()
+	log.Printf("Retomando %d buscas não concluídas", len(jobs))
+}
+
+func (q *searchQueue) worker(id int) {
+	defer q.workers.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case job := <-q.jobs:
+			// O select escolhe ao acaso quando as duas opções estão prontas
+			if q.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Worker %d executando a busca %d", id, job.ProgressID)
+			q.run(q.ctx, job)
+		}
+	}
+}
+
+func (q *searchQueue) run(parent context.Context, job searchJob) {
+	progress, err := repository.GetSearchProgressByID(q.db, job.ProgressID)
+	if err != nil {
+		log.Printf("Erro ao carregar a busca %d: %v", job.ProgressID, err)
+		return
+	}
+	if progress.Status == repository.SearchStatusCancelled {
+		log.Printf("Busca %d foi cancelada antes de começar", job.ProgressID)
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	q.track(job.ProgressID, cancel)
+	defer func() {
+		q.untrack(job.ProgressID)
+		cancel(nil)
+	}()
+
+	err = repository.UpdateSearchProgressStatus(q.db, job.ProgressID, repository.SearchStatusRunning)
+	if err != nil {
+		log.Printf("Erro ao marcar a busca %d como em execução: %v", job.ProgressID, err)
+	}
+
+	status := repository.SearchStatusDone
+	provider := googleplaces.NewRetryingProvider(
+		meteredProviderFor(q.db, q.provider, q.limiter, job.ProgressID),
+		googleplaces.DefaultRetryPolicy,
+	)
+	err = startSearch(ctx, job, q.db, provider, q.publisher)
+	switch {
+	case err != nil && searchInterrupted(ctx):
+		status = repository.SearchStatusInterrupted
+		log.Printf("Busca %d interrompida, será retomada quando o serviço subir de novo", job.ProgressID)
+	case err != nil && errors.Is(context.Cause(ctx), errSearchCancelled):
+		status = repository.SearchStatusCancelled
+		log.Printf("Busca %d cancelada", job.ProgressID)
+	case err != nil:
+		status = repository.SearchStatusFailed
+		log.Printf("Busca %d falhou: %v", job.ProgressID, err)
+		if recordErr := repository.RecordSearchError(q.db, job.ProgressID, err.Error()); recordErr != nil {
+			log.Printf("Erro ao registrar falha da busca %d: %v", job.ProgressID, recordErr)
+		}
+	}
+
+	err = repository.UpdateSearchProgressStatus(q.db, job.ProgressID, status)
+	if err != nil {
+		log.Printf("Erro ao atualizar o status da busca %d: %v", job.ProgressID, err)
+	}
+
+	if job.ZipcodeID != 0 {
+		if err := repository.RefreshZipcodeStatus(q.db, job.ZipcodeID); err != nil {
+			log.Printf("Erro ao atualizar o status do zipcode %d: %v", job.ZipcodeID, err)
+		}
+	}
+	if err := repository.RefreshCategoryStatus(q.db, job.CategoryID); err != nil {
+		log.Printf("Erro ao atualizar o status da categoria %s: %v", job.CategoryID, err)
+	}
+}
+
+func (q *searchQueue) track(progressID int64, cancel context.CancelCauseFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running == nil {
+		q.running = make(map[int64]context.CancelCauseFunc)
+	}
+	q.running[progressID] = cancel
+}
+
+func (q *searchQueue) untrack(progressID int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, progressID)
+}
+
+// Indica se a busca parou por causa do desligamento do serviço
+func searchInterrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errSearchInterrupted)
+}
+
+func searchProgressFor(job searchJob, locationInfo *repository.LocationInfo) repository.SearchProgress {
+	return repository.SearchProgress{
+		ScheduleID:  job.ScheduleID,
+		CategoriaID: job.CategoryID,
+		CountryID:   locationInfo.CountryID,
+		StateID:     locationInfo.StateID,
+		CityID:      locationInfo.CityID,
+		DistrictID:  locationInfo.DistrictID,
+		ZipcodeID:   locationInfo.ZipcodeID,
+		Radius:      job.Radius,
+		Mode:        job.Mode,
+		MaxResults:  job.MaxResults,
+		MaxPages:    job.MaxPages,
+		Status:      repository.SearchStatusQueued,
+	}
+}
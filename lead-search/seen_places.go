@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"lead-search/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Lugares publicados há menos tempo que isso não são consultados de novo;
+// PLACE_REFRESH_AFTER=0 consulta sempre
+const defaultPlaceRefreshAfter = 90 * 24 * time.Hour
+
+var placesSkipped = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "leadsearch_places_skipped_total",
+		Help: "Lugares já conhecidos cujos detalhes não foram consultados de novo",
+	},
+	[]string{"category_id"},
+)
+
+// Separa os place IDs que precisam de uma chamada de Details dos que já foram
+// publicados dentro de refreshAfter. Se o registro não puder ser lido, todos
+// são consultados.
+func filterSeenPlaces(db *sql.DB, placeIDs []string, refreshAfter time.Duration, now time.Time) ([]string, int) {
+	if refreshAfter <= 0 || len(placeIDs) == 0 {
+		return placeIDs, 0
+	}
+
+	fetched, err := repository.GetPlaceFetchTimes(db, placeIDs)
+	if err != nil {
+		log.Printf("Erro ao consultar os lugares já conhecidos: %v", err)
+		return placeIDs, 0
+	}
+
+	fresh := make([]string, 0, len(placeIDs))
+	for _, placeID := range placeIDs {
+		lastFetchedAt, seen := fetched[placeID]
+		if seen && now.Sub(lastFetchedAt) < refreshAfter {
+			continue
+		}
+		fresh = append(fresh, placeID)
+	}
+	return fresh, len(placeIDs) - len(fresh)
+}
+
+func markPlaceFetched(db *sql.DB, placeID string, progressID int64) {
+	if err := repository.MarkPlaceFetched(db, placeID, progressID, time.Now()); err != nil {
+		log.Printf("%v", err)
+	}
+}
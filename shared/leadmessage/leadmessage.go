@@ -0,0 +1,159 @@
+// Package leadmessage define a mensagem de lead publicada pelo lead-search no
+// leads_exchange e consumida pela api.
+//
+// As chaves JSON são as mesmas do formato antigo em map[string]interface{},
+// para que consumidores que leem o JSON direto (website-fetcher) continuem
+// funcionando. Mudanças incompatíveis precisam de um novo SchemaVersion.
+package leadmessage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Versão atual do schema, enviada no corpo e no header VersionHeader
+const SchemaVersion = 1
+
+// Header AMQP com a versão do schema da mensagem
+const VersionHeader = "x-schema-version"
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported lead message schema version")
+	ErrInvalidMessage     = errors.New("invalid lead message")
+)
+
+type LeadMessage struct {
+	SchemaVersion int `json:"SchemaVersion"`
+
+	PlaceID                  string   `json:"PlaceID"`
+	Name                     string   `json:"Name"`
+	FormattedAddress         string   `json:"FormattedAddress"`
+	City                     string   `json:"City"` // cidade do endereço do lugar
+	State                    string   `json:"State"`
+	ZIPCode                  string   `json:"ZIPCode"`
+	Country                  string   `json:"Country"`
+	InternationalPhoneNumber string   `json:"InternationalPhoneNumber,omitempty"`
+	Email                    string   `json:"Email,omitempty"`
+	Website                  string   `json:"Website,omitempty"`
+	Description              string   `json:"Description,omitempty"`
+	Rating                   float64  `json:"Rating"`
+	UserRatingsTotal         int      `json:"UserRatingsTotal"`
+	PriceLevel               int      `json:"PriceLevel"`
+	BusinessStatus           string   `json:"BusinessStatus,omitempty"`
+	Vicinity                 string   `json:"Vicinity,omitempty"`
+	PermanentlyClosed        bool     `json:"PermanentlyClosed"`
+	Types                    []string `json:"Types,omitempty"`
+	OpeningHours             []string `json:"OpeningHours,omitempty"`
+
+	// Busca que encontrou o lugar
+	Category   string `json:"Category"`
+	SearchCity string `json:"SearchCity"`
+	Radius     int    `json:"Radius"`
+}
+
+// Confere os campos obrigatórios e as faixas de valores da API do Google
+func (m LeadMessage) Validate() error {
+	var problems []string
+	if m.SchemaVersion != SchemaVersion {
+		problems = append(problems, fmt.Sprintf("schema version %d", m.SchemaVersion))
+	}
+	if strings.TrimSpace(m.PlaceID) == "" {
+		problems = append(problems, "missing PlaceID")
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		problems = append(problems, "missing Name")
+	}
+	if m.Rating < 0 || m.Rating > 5 {
+		problems = append(problems, fmt.Sprintf("Rating %.2f out of range 0-5", m.Rating))
+	}
+	if m.UserRatingsTotal < 0 {
+		problems = append(problems, fmt.Sprintf("negative UserRatingsTotal %d", m.UserRatingsTotal))
+	}
+	if m.PriceLevel < 0 || m.PriceLevel > 4 {
+		problems = append(problems, fmt.Sprintf("PriceLevel %d out of range 0-4", m.PriceLevel))
+	}
+	if m.Radius < 0 {
+		problems = append(problems, fmt.Sprintf("negative Radius %d", m.Radius))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidMessage, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Serializa a mensagem com a versão atual do schema, depois de validá-la
+func Encode(m LeadMessage) ([]byte, error) {
+	m.SchemaVersion = SchemaVersion
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// Lê a mensagem recebida. headerVersion é o valor do header VersionHeader
+// (nil quando ausente); versões desconhecidas e campos com tipo errado são
+// rejeitados em vez de preencher o lead pela metade.
+func Decode(headerVersion interface{}, body []byte) (LeadMessage, error) {
+	var m LeadMessage
+
+	version, err := ParseVersion(headerVersion)
+	if err != nil {
+		return m, err
+	}
+	if version != SchemaVersion {
+		return m, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if m.SchemaVersion != version {
+		return m, fmt.Errorf("%w: header says %d, body says %d", ErrUnsupportedVersion, version, m.SchemaVersion)
+	}
+	return m, m.Validate()
+}
+
+// Converte o valor do header, que chega como inteiro de tamanhos variados ou texto
+func ParseVersion(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, fmt.Errorf("%w: missing %s header", ErrUnsupportedVersion, VersionHeader)
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case string:
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrUnsupportedVersion, v)
+		}
+		return version, nil
+	}
+	return 0, fmt.Errorf("%w: header of type %T", ErrUnsupportedVersion, value)
+}
+
+// Lê só a versão do corpo já serializado (ex.: mensagens guardadas no outbox)
+func PeekVersion(body []byte) (int, error) {
+	var header struct {
+		SchemaVersion int `json:"SchemaVersion"`
+	}
+	if err := json.Unmarshal(body, &header); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return header.SchemaVersion, nil
+}
@@ -0,0 +1,50 @@
+package leadmessage
+
+import (
+	"errors"
+	"testing"
+)
+
+func validMessage() LeadMessage {
+	return LeadMessage{
+		PlaceID:          "place_a",
+		Name:             "Padaria Bela Paulista",
+		Rating:           4.4,
+		UserRatingsTotal: 120,
+		Category:         "padaria",
+		SearchCity:       "São Paulo",
+		Radius:           500,
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	body, err := Encode(validMessage())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// O header chega como int32 pelo streadway/amqp
+	decoded, err := Decode(int32(SchemaVersion), body)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.PlaceID != "place_a" || decoded.UserRatingsTotal != 120 || decoded.Radius != 500 {
+		t.Errorf("unexpected decoded message: %+v", decoded)
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	body, _ := Encode(validMessage())
+	for _, header := range []interface{}{nil, int32(SchemaVersion + 1), "v2"} {
+		if _, err := Decode(header, body); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("header %v: expected ErrUnsupportedVersion, got %v", header, err)
+		}
+	}
+}
+
+func TestDecodeRejectsMistypedFields(t *testing.T) {
+	body := []byte(`{"SchemaVersion":1,"PlaceID":"place_a","Name":"Padaria","UserRatingsTotal":"many"}`)
+	if _, err := Decode(int32(SchemaVersion), body); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("expected ErrInvalidMessage, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package phone interpreta telefones brasileiros (fixos e celulares) nos
+// formatos que chegam ao pipeline: E.164 do Google Places ("+55 11 2892-1688"),
+// números locais dos dados de CNPJ ("(11) 98765-4321", "1128921688") e
+// variações com prefixo de operadora ("0 21 11 2892-1688").
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Código do Brasil
+const CountryCode = "55"
+
+type Kind string
+
+const (
+	KindMobile Kind = "mobile"
+	KindFixed  Kind = "fixed"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DDDs em uso no Brasil
+var validDDDs = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true, "22": true, "24": true, "27": true, "28": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "37": true, "38": true,
+	"41": true, "42": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "53": true, "54": true, "55": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "67": true, "68": true, "69": true,
+	"71": true, "73": true, "74": true, "75": true, "77": true, "79": true,
+	"81": true, "82": true, "83": true, "84": true, "85": true, "86": true, "87": true, "88": true, "89": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true, "98": true, "99": true,
+}
+
+type Number struct {
+	DDD        string
+	Subscriber string // 8 dígitos (fixo) ou 9 dígitos começando com 9 (celular)
+	Kind       Kind
+}
+
+// +5511928921688
+func (n Number) E164() string {
+	return "+" + CountryCode + n.DDD + n.Subscriber
+}
+
+// (11) 2892-1688 ou (11) 98765-4321
+func (n Number) Format() string {
+	split := len(n.Subscriber) - 4
+	return fmt.Sprintf("(%s) %s-%s", n.DDD, n.Subscriber[:split], n.Subscriber[split:])
+}
+
+func (n Number) String() string {
+	return n.E164()
+}
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// Interpreta um número com DDD
+func Parse(raw string) (Number, error) {
+	return ParseWithDDD(raw, "")
+}
+
+// Como Parse, mas aceita números sem DDD usando defaultDDD
+func ParseWithDDD(raw string, defaultDDD string) (Number, error) {
+	trimmed := strings.TrimSpace(raw)
+	digits := nonDigits.ReplaceAllString(trimmed, "")
+	if digits == "" {
+		return Number{}, fmt.Errorf("%w: %q", ErrInvalidPhone, raw)
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "+"):
+		// Com código de país, só aceita o do Brasil
+		if !strings.HasPrefix(digits, CountryCode) {
+			return Number{}, fmt.Errorf("%w: %q is not a Brazilian number", ErrInvalidPhone, raw)
+		}
+		digits = digits[len(CountryCode):]
+	case strings.HasPrefix(digits, "00"+CountryCode):
+		digits = digits[2+len(CountryCode):]
+	case strings.HasPrefix(digits, CountryCode) && len(digits) >= 12 && len(digits) <= 13:
+		digits = digits[len(CountryCode):]
+	case strings.HasPrefix(digits, "0"):
+		// Prefixo de longa distância, às vezes com o código da operadora
+		digits = digits[1:]
+		if len(digits) == 12 || len(digits) == 13 {
+			digits = digits[2:]
+		}
+	}
+
+	if (len(digits) == 8 || len(digits) == 9) && defaultDDD != "" {
+		digits = defaultDDD + digits
+	}
+
+	if len(digits) != 10 && len(digits) != 11 {
+		return Number{}, fmt.Errorf("%w: %q has %d digits", ErrInvalidPhone, raw, len(digits))
+	}
+
+	number := Number{DDD: digits[:2], Subscriber: digits[2:]}
+	if !validDDDs[number.DDD] {
+		return Number{}, fmt.Errorf("%w: %q has unknown DDD %s", ErrInvalidPhone, raw, number.DDD)
+	}
+
+	switch {
+	case len(number.Subscriber) == 9:
+		if number.Subscriber[0] != '9' {
+			return Number{}, fmt.Errorf("%w: %q is a 9-digit number not starting with 9", ErrInvalidPhone, raw)
+		}
+		number.Kind = KindMobile
+	case number.Subscriber[0] >= '2' && number.Subscriber[0] <= '5':
+		number.Kind = KindFixed
+	case number.Subscriber[0] >= '6':
+		// Celular antigo, anterior ao nono dígito
+		number.Subscriber = "9" + number.Subscriber
+		number.Kind = KindMobile
+	default:
+		return Number{}, fmt.Errorf("%w: %q", ErrInvalidPhone, raw)
+	}
+	return number, nil
+}
+
+// Separa e interpreta vários números em um mesmo texto ("(11) 2892-1688,
+// (11) 98765-4321" ou "11 2892-1688 / 11 98765-4321"). Números repetidos
+// aparecem uma vez; os inválidos vêm nos erros.
+func ParseAll(raw string) ([]Number, []error) {
+	var numbers []Number
+	var errs []error
+	seen := make(map[string]bool)
+
+	parts := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '/' || r == '|' || r == '\n'
+	})
+	for _, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		number, err := Parse(part)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[number.E164()] {
+			continue
+		}
+		seen[number.E164()] = true
+		numbers = append(numbers, number)
+	}
+	return numbers, errs
+}
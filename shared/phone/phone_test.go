@@ -0,0 +1,68 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		raw  string
+		e164 string
+		kind Kind
+	}{
+		{"+55 11 2892-1688", "+551128921688", KindFixed},
+		{"(11) 98765-4321", "+5511987654321", KindMobile},
+		{"1128921688", "+551128921688", KindFixed},
+		{"5511987654321", "+5511987654321", KindMobile},
+		{"011 2892-1688", "+551128921688", KindFixed},
+		{"0 21 11 98765-4321", "+5511987654321", KindMobile},
+		{"(21) 8765-4321", "+5521987654321", KindMobile}, // sem o nono dígito
+		{"0055 48 3222-1000", "+554832221000", KindFixed},
+		{"11 2892-1688 (comercial)", "+551128921688", KindFixed},
+	}
+	for _, c := range cases {
+		number, err := Parse(c.raw)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", c.raw, err)
+			continue
+		}
+		if number.E164() != c.e164 || number.Kind != c.kind {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", c.raw, c.e164, c.kind, number.E164(), number.Kind)
+		}
+	}
+}
+
+func TestParseRejectsInvalidNumbers(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"2892-1688",       // sem DDD
+		"(20) 2892-1688",  // DDD inexistente
+		"(11) 88765-4321", // 9 dígitos sem começar com 9
+		"(11) 1892-1688",  // fixo começando com 1
+		"+1 415 555 0100", // fora do Brasil
+		"0800 123 4567",   // não geográfico
+	} {
+		_, err := Parse(raw)
+		if !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("%q: expected ErrInvalidPhone, got %v", raw, err)
+		}
+	}
+}
+
+func TestParseWithDDD(t *testing.T) {
+	number, err := ParseWithDDD("98765-4321", "31")
+	if err != nil || number.E164() != "+5531987654321" {
+		t.Errorf("expected +5531987654321, got %s (err %v)", number.E164(), err)
+	}
+}
+
+func TestParseAllSplitsAndDeduplicates(t *testing.T) {
+	numbers, errs := ParseAll("+55 11 2892-1688, (11) 2892-1688 / (11) 98765-4321; abc")
+	if len(numbers) != 2 || numbers[0].Format() != "(11) 2892-1688" || numbers[1].Format() != "(11) 98765-4321" {
+		t.Errorf("unexpected numbers: %v", numbers)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}